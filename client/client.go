@@ -121,6 +121,78 @@ func (c *Client) Match(ctx context.Context, req *service.MatchReq, f func(*graph
 	}
 }
 
+// Aggregate calls the eponymous method of the service.
+func (c *Client) Aggregate(ctx context.Context, req *service.AggregateReq) (*service.AggregateRsp, error) {
+	var rsp service.AggregateRsp
+	if err := c.cli.CallResult(ctx, "Aggregate", req, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+// Cycles calls the eponymous method of the service.
+func (c *Client) Cycles(ctx context.Context, req *service.CyclesReq) (*service.CyclesRsp, error) {
+	var rsp service.CyclesRsp
+	if err := c.cli.CallResult(ctx, "Cycles", req, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+// Diff calls the eponymous method of the service.
+func (c *Client) Diff(ctx context.Context, req *service.DiffReq) (*service.DiffRsp, error) {
+	var rsp service.DiffRsp
+	if err := c.cli.CallResult(ctx, "Diff", req, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+// Impact calls the eponymous method of the service.
+func (c *Client) Impact(ctx context.Context, req *service.ImpactReq) (*service.ImpactRsp, error) {
+	var rsp service.ImpactRsp
+	if err := c.cli.CallResult(ctx, "Impact", req, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+// Layers calls the eponymous method of the service.
+func (c *Client) Layers(ctx context.Context, req *service.LayersReq) (*service.LayersRsp, error) {
+	var rsp service.LayersRsp
+	if err := c.cli.CallResult(ctx, "Layers", req, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+// Path calls the eponymous method of the service.
+func (c *Client) Path(ctx context.Context, req *service.PathReq) (*service.PathRsp, error) {
+	var rsp service.PathRsp
+	if err := c.cli.CallResult(ctx, "Path", req, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+// Query calls the eponymous method of the service.
+func (c *Client) Query(ctx context.Context, req *service.QueryReq) (*service.QueryRsp, error) {
+	var rsp service.QueryRsp
+	if err := c.cli.CallResult(ctx, "Query", req, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+// Search calls the eponymous method of the service.
+func (c *Client) Search(ctx context.Context, req *service.SearchReq) (*service.SearchRsp, error) {
+	var rsp service.SearchRsp
+	if err := c.cli.CallResult(ctx, "Search", req, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
 // Resolve calls the eponymous method of the service.
 func (c *Client) Resolve(ctx context.Context, pkg string) (*service.ResolveRsp, error) {
 	var rsp service.ResolveRsp
@@ -161,6 +233,161 @@ func (c *Client) Reverse(ctx context.Context, req *service.ReverseReq, f func(*s
 	}
 }
 
+// Closure calls the eponymous method of the service and delivers a result to
+// f for each package in the closure. If f reports an error, pagination stops
+// and that error is reported to the caller of Closure. The total number of
+// packages is returned.
+//
+// Since the service recomputes the closure for each page, if req.Limit is
+// not positive the whole closure is fetched in a single call.
+func (c *Client) Closure(ctx context.Context, req *service.ClosureReq, f func(*service.ClosureDep) error) (int, error) {
+	cp := *req
+	lim := cp.Limit
+	if lim <= 0 {
+		cp.Limit = -1
+	}
+	nr := 0
+	for {
+		var rsp service.ClosureRsp
+		if err := c.cli.CallResult(ctx, "Closure", &cp, &rsp); err != nil {
+			return nr, err
+		} else if req.CountOnly {
+			return rsp.NumPackages, nil
+		}
+		for _, dep := range rsp.Packages {
+			err := f(dep)
+			nr++
+			if err != nil {
+				return nr, err
+			} else if lim > 0 && nr == lim {
+				return nr, nil
+			}
+		}
+		if rsp.NextPage == nil {
+			return nr, nil
+		}
+		cp.PageKey = rsp.NextPage
+	}
+}
+
+// Orphans calls the eponymous method of the service and delivers a result to
+// f for each orphan found. If f reports an error, pagination stops and that
+// error is reported to the caller of Orphans. The total number of orphans is
+// returned.
+func (c *Client) Orphans(ctx context.Context, req *service.OrphansReq, f func(*service.Orphan) error) (int, error) {
+	cp := *req
+	lim := cp.Limit
+	nr := 0
+	for {
+		var rsp service.OrphansRsp
+		if err := c.cli.CallResult(ctx, "Orphans", &cp, &rsp); err != nil {
+			return nr, err
+		} else if req.CountOnly {
+			return rsp.NumOrphans, nil
+		}
+		for _, orphan := range rsp.Orphans {
+			err := f(orphan)
+			nr++
+			if err != nil {
+				return nr, err
+			} else if lim > 0 && nr == lim {
+				return nr, nil
+			}
+		}
+		if rsp.NextPage == nil {
+			return nr, nil
+		}
+		cp.PageKey = rsp.NextPage
+	}
+}
+
+// Dump calls the eponymous method of the service and delivers each record of
+// the dump to f, beginning with the header. If f reports an error, pagination
+// stops and that error is reported to the caller of Dump. The total number of
+// records delivered is returned.
+func (c *Client) Dump(ctx context.Context, req *service.DumpReq, f func(*service.DumpRecord) error) (int, error) {
+	cp := *req
+	nr := 0
+	for {
+		var rsp service.DumpRsp
+		if err := c.cli.CallResult(ctx, "Dump", &cp, &rsp); err != nil {
+			return nr, err
+		}
+		for _, rec := range rsp.Records {
+			nr++
+			if err := f(rec); err != nil {
+				return nr, err
+			}
+		}
+		if rsp.NextPage == nil {
+			return nr, nil
+		}
+		cp.PageKey = rsp.NextPage
+	}
+}
+
+// Visibility calls the eponymous method of the service and delivers a result
+// to f for each violation found. If f reports an error, pagination stops and
+// that error is reported to the caller of Visibility. The total number of
+// violations is returned.
+func (c *Client) Visibility(ctx context.Context, req *service.VisibilityReq, f func(*service.ReverseDep) error) (int, error) {
+	cp := *req
+	lim := cp.Limit
+	nr := 0
+	for {
+		var rsp service.VisibilityRsp
+		if err := c.cli.CallResult(ctx, "Visibility", &cp, &rsp); err != nil {
+			return nr, err
+		} else if req.CountOnly {
+			return rsp.NumViolations, nil
+		}
+		for _, dep := range rsp.Violations {
+			err := f(dep)
+			nr++
+			if err != nil {
+				return nr, err
+			} else if lim > 0 && nr == lim {
+				return nr, nil
+			}
+		}
+		if rsp.NextPage == nil {
+			return nr, nil
+		}
+		cp.PageKey = rsp.NextPage
+	}
+}
+
+// Vendored calls the eponymous method of the service and delivers a result to
+// f for each vendored module found. If f reports an error, pagination stops
+// and that error is reported to the caller of Vendored. The total number of
+// matching results is returned.
+func (c *Client) Vendored(ctx context.Context, req *service.VendoredReq, f func(*service.VendoredModule) error) (int, error) {
+	cp := *req
+	lim := cp.Limit
+	nr := 0
+	for {
+		var rsp service.VendoredRsp
+		if err := c.cli.CallResult(ctx, "Vendored", &cp, &rsp); err != nil {
+			return nr, err
+		} else if req.CountOnly {
+			return rsp.NumModules, nil
+		}
+		for _, mod := range rsp.Repos {
+			err := f(mod)
+			nr++
+			if err != nil {
+				return nr, err
+			} else if lim > 0 && nr == lim {
+				return nr, nil
+			}
+		}
+		if rsp.NextPage == nil {
+			return nr, nil
+		}
+		cp.PageKey = rsp.NextPage
+	}
+}
+
 // RepoStatus calls the eponymous method of the service.
 func (c *Client) RepoStatus(ctx context.Context, repo string) (*service.RepoStatusRsp, error) {
 	var rsp service.RepoStatusRsp
@@ -186,6 +413,20 @@ func (c *Client) Scan(ctx context.Context, req *service.ScanReq) (*service.ScanR
 	return &rsp, nil
 }
 
+// Fsck calls the eponymous method of the service. If the server requires a
+// write token, the caller must provide one via SetToken.
+func (c *Client) Fsck(ctx context.Context, req *service.FsckReq) (*service.FsckRsp, error) {
+	ctx, err := jctx.WithMetadata(ctx, c.token)
+	if err != nil {
+		return nil, fmt.Errorf("write token: %v", err)
+	}
+	var rsp service.FsckRsp
+	if err := c.cli.CallResult(ctx, "Fsck", req, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
 // Rank calls the eponymous method of the service. If the server requires a
 // write token, the caller must provide one via SetToken.
 func (c *Client) Rank(ctx context.Context, req *service.RankReq) (*service.RankRsp, error) {
@@ -200,6 +441,48 @@ func (c *Client) Rank(ctx context.Context, req *service.RankReq) (*service.RankR
 	return &rsp, nil
 }
 
+// Reindex calls the eponymous method of the service. If the server requires
+// a write token, the caller must provide one via SetToken.
+func (c *Client) Reindex(ctx context.Context, req *service.ReindexReq) (*service.ReindexRsp, error) {
+	ctx, err := jctx.WithMetadata(ctx, c.token)
+	if err != nil {
+		return nil, fmt.Errorf("write token: %v", err)
+	}
+	var rsp service.ReindexRsp
+	if err := c.cli.CallResult(ctx, "Reindex", req, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+// Remove calls the eponymous method of the service. If the server requires a
+// write token, the caller must provide one via SetToken.
+func (c *Client) Remove(ctx context.Context, req *service.RemoveReq) (*service.RemoveRsp, error) {
+	ctx, err := jctx.WithMetadata(ctx, c.token)
+	if err != nil {
+		return nil, fmt.Errorf("write token: %v", err)
+	}
+	var rsp service.RemoveRsp
+	if err := c.cli.CallResult(ctx, "Remove", req, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+// Restore calls the eponymous method of the service. If the server requires
+// a write token, the caller must provide one via SetToken.
+func (c *Client) Restore(ctx context.Context, req *service.RestoreReq) (*service.RestoreRsp, error) {
+	ctx, err := jctx.WithMetadata(ctx, c.token)
+	if err != nil {
+		return nil, fmt.Errorf("write token: %v", err)
+	}
+	var rsp service.RestoreRsp
+	if err := c.cli.CallResult(ctx, "Restore", req, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
 // Update calls the eponymous method of the service. If the server requires a
 // write token, the caller must provide one via SetToken.
 func (c *Client) Update(ctx context.Context, req *service.UpdateReq) (*service.UpdateRsp, error) {
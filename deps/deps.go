@@ -36,6 +36,36 @@ type Options struct {
 	TrimRepoPrefix    bool   `json:"trimRepoPrefix"`    // trim the repository prefix from each package
 	StandardLibrary   bool   `json:"standardLibrary"`   // treat the inputs as standard libraries
 	PackagePrefix     string `json:"packagePrefix"`     // attribute this package prefix to repo contents
+	SkipSubmodules    bool   `json:"skipSubmodules"`    // do not load packages from git submodules
+
+	// Select which remote identifies a repository with several remotes.
+	RemotePolicy *RemotePolicy `json:"remotePolicy,omitempty"`
+}
+
+// A RemotePolicy selects which of the remotes of a repository is used to
+// identify it and to name its packages. A nil *RemotePolicy selects the first
+// remote listed.
+type RemotePolicy struct {
+	// If non-empty, consider only remotes whose URL has one of these hosts.
+	Hosts []string `json:"hosts,omitempty"`
+
+	// If true, prefer remotes whose URL is a prefix of the module path
+	// declared by the go.mod file at the root of the repository.
+	MatchModule bool `json:"matchModule,omitempty"`
+
+	// If non-empty, prefer remotes with these names, in order of preference,
+	// e.g., "upstream", "origin".
+	Names []string `json:"names,omitempty"`
+}
+
+// URL returns the URL that identifies r, or "" if r has no remotes.
+func (r *Repo) URL() string {
+	if r.PrimaryUrl != "" {
+		return r.PrimaryUrl
+	} else if len(r.Remotes) != 0 {
+		return r.Remotes[0].Url
+	}
+	return ""
 }
 
 // Hash produces a SHA-256 digest of the contents of r.
@@ -101,6 +131,64 @@ func HasDomain(ip string) (string, bool) {
 	return prefix, strings.Index(prefix, ".") > 0
 }
 
+// CanImport reports whether the package with import path src is permitted to
+// import the package with import path dst, according to the visibility rules
+// for internal packages: A package whose import path contains an "internal"
+// element may only be imported by packages rooted at the parent of the last
+// such element. A top-level internal package may only be imported by packages
+// that do not begin with a domain (e.g., the standard library).
+func CanImport(src, dst string) bool {
+	var parent string
+	switch {
+	case strings.HasSuffix(dst, "/internal"):
+		parent = strings.TrimSuffix(dst, "internal")
+	case strings.Contains(dst, "/internal/"):
+		parent = dst[:strings.LastIndex(dst, "/internal/")+1]
+	case dst == "internal", strings.HasPrefix(dst, "internal/"):
+		_, ok := HasDomain(src)
+		return !ok
+	default:
+		return true // not an internal package
+	}
+	return strings.HasPrefix(src, parent) || src == strings.TrimSuffix(parent, "/")
+}
+
+// ParseVendorModules parses the contents of a vendor/modules.txt file, as
+// written by "go mod vendor", and returns the modules it records in order of
+// appearance.
+func ParseVendorModules(data []byte) []*Module {
+	var mods []*Module
+	var cur *Module
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "## "):
+			// blank, or annotations ("## explicit; go 1.17")
+
+		case strings.HasPrefix(line, "# "):
+			// Module: # path [version] [=> path [version]]
+			lhs, rhs := line[2:], ""
+			if i := strings.Index(lhs, "=>"); i >= 0 {
+				lhs, rhs = lhs[:i], strings.TrimSpace(lhs[i+2:])
+			}
+			fields := strings.Fields(lhs)
+			if len(fields) == 0 {
+				cur = nil
+				continue // malformed; ignore it and its packages
+			}
+			cur = &Module{Path: fields[0], Replace: rhs}
+			if len(fields) > 1 {
+				cur.Version = fields[1]
+			}
+			mods = append(mods, cur)
+
+		case cur != nil:
+			cur.Packages = append(cur.Packages, line)
+		}
+	}
+	return mods
+}
+
 // A PathLabelMap maintains an association between paths and labels, and
 // assigns subpaths that do not have their own labels a label based on the
 // nearest enclosing parent.
@@ -125,3 +213,20 @@ func (p PathLabelMap) Find(path string) (string, bool) {
 	}
 	return "", false
 }
+
+// Enclosing returns the label of path, or of its nearest enclosing parent path
+// with a label. Unlike Find, the label is not extended. Enclosing returns "",
+// false if no matching label is found.
+func (p PathLabelMap) Enclosing(path string) (string, bool) {
+	for cur := path; cur != ""; {
+		if label, ok := p[cur]; ok {
+			return label, true
+		}
+		i := strings.LastIndex(cur, "/")
+		if i < 0 {
+			break
+		}
+		cur = cur[:i]
+	}
+	return "", false
+}
@@ -0,0 +1,83 @@
+package deps_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/creachadair/repodeps/deps"
+)
+
+func TestCanImport(t *testing.T) {
+	tests := []struct {
+		src, dst string
+		want     bool
+	}{
+		// Non-internal packages are visible everywhere.
+		{"github.com/a/b", "github.com/c/d", true},
+		{"github.com/a/b", "github.com/c/internals/d", true},
+		{"github.com/a/b", "github.com/c/xinternal", true},
+
+		// Internal packages are visible within their parent.
+		{"github.com/a/b", "github.com/a/b/internal", true},
+		{"github.com/a/b", "github.com/a/b/internal/c", true},
+		{"github.com/a/b/c", "github.com/a/b/internal/c", true},
+		{"github.com/a/b/internal/c", "github.com/a/b/internal/d", true},
+		{"github.com/a/b/x/y", "github.com/a/b/internal", true},
+
+		// Internal packages are not visible outside their parent.
+		{"github.com/a/bc", "github.com/a/b/internal", false},
+		{"github.com/a/c", "github.com/a/b/internal/c", false},
+		{"github.com/fork/b", "github.com/a/b/internal/c", false},
+
+		// The last internal element governs.
+		{"github.com/a/b", "github.com/a/b/internal/c/internal/d", false},
+		{"github.com/a/b/internal/c", "github.com/a/b/internal/c/internal/d", true},
+
+		// Top-level internal packages are visible only to non-domain packages.
+		{"os", "internal/poll", true},
+		{"net/http", "internal", true},
+		{"github.com/a/b", "internal/poll", false},
+	}
+	for _, test := range tests {
+		got := deps.CanImport(test.src, test.dst)
+		if got != test.want {
+			t.Errorf("CanImport(%q, %q): got %v, want %v", test.src, test.dst, got, test.want)
+		}
+	}
+}
+
+func TestParseVendorModules(t *testing.T) {
+	const input = `# github.com/foo/bar v1.2.3
+## explicit
+github.com/foo/bar
+github.com/foo/bar/baz
+# golang.org/x/net v0.0.1 => golang.org/x/net v0.0.2
+## explicit; go 1.17
+golang.org/x/net/context
+# example.com/local v1.0.0 => ../local
+# example.com/gone => example.com/fork v1.0.0
+`
+	mods := deps.ParseVendorModules([]byte(input))
+	want := []struct {
+		path, version, replace string
+		pkgs                   []string
+	}{
+		{"github.com/foo/bar", "v1.2.3", "", []string{"github.com/foo/bar", "github.com/foo/bar/baz"}},
+		{"golang.org/x/net", "v0.0.1", "golang.org/x/net v0.0.2", []string{"golang.org/x/net/context"}},
+		{"example.com/local", "v1.0.0", "../local", nil},
+		{"example.com/gone", "", "example.com/fork v1.0.0", nil},
+	}
+	if len(mods) != len(want) {
+		t.Fatalf("ParseVendorModules: got %d modules, want %d", len(mods), len(want))
+	}
+	for i, w := range want {
+		got := mods[i]
+		if got.Path != w.path || got.Version != w.version || got.Replace != w.replace {
+			t.Errorf("Module %d: got (%q, %q, %q), want (%q, %q, %q)",
+				i, got.Path, got.Version, got.Replace, w.path, w.version, w.replace)
+		}
+		if fmt.Sprint(got.Packages) != fmt.Sprint(w.pkgs) {
+			t.Errorf("Module %d packages: got %q, want %q", i, got.Packages, w.pkgs)
+		}
+	}
+}
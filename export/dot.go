@@ -0,0 +1,64 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteDOT writes sg to w as a Graphviz DOT digraph. Nodes are identified by
+// package name, and carry the other node attributes as DOT attributes.
+// Programs are drawn as boxes, and packages without a row as dashed ellipses.
+//
+// DOT output is intended for small subgraphs; Graphviz does not lay out large
+// graphs in reasonable time.
+func WriteDOT(w io.Writer, sg *Subgraph) error {
+	buf := bufio.NewWriter(w)
+	fmt.Fprintln(buf, "digraph deps {")
+	for _, n := range sg.Nodes {
+		var attrs []string
+		vals := n.values()
+		for i, v := range vals[1:] {
+			if v != "" {
+				attrs = append(attrs, nodeAttrs[i+1].name+"="+dotQuote(v))
+			}
+		}
+		switch {
+		case n.Type == "program":
+			attrs = append(attrs, "shape=box")
+		case n.Type == "":
+			attrs = append(attrs, "style=dashed")
+		}
+		fmt.Fprintf(buf, "  %s", dotQuote(n.Package))
+		if len(attrs) != 0 {
+			fmt.Fprintf(buf, " [%s]", strings.Join(attrs, ", "))
+		}
+		fmt.Fprintln(buf, ";")
+	}
+	for _, e := range sg.Edges {
+		fmt.Fprintf(buf, "  %s -> %s;\n", dotQuote(e.Source), dotQuote(e.Target))
+	}
+	fmt.Fprintln(buf, "}")
+	return buf.Flush()
+}
+
+// dotQuote returns s as a quoted DOT string. DOT strings escape only quotation
+// marks; a backslash is escaped too so that it is not read as an escape.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,186 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package export writes subgraphs of a dependency graph in formats used by
+// graph visualization tools: GraphML, GEXF, and Graphviz DOT.
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/creachadair/repodeps/graph"
+	"github.com/creachadair/repodeps/storage"
+)
+
+// Options control the selection of a subgraph by Select. A nil *Options
+// selects the entire graph.
+type Options struct {
+	// Select the packages whose import paths have this prefix. If Prefix is
+	// empty and there are no Roots, every package with a row is selected.
+	Prefix string
+
+	// Select these packages in addition to any matching Prefix.
+	Roots []string
+
+	// If positive, also select the packages reachable from the selected
+	// packages by a chain of at most this many direct dependencies.
+	MaxDepth int
+
+	// If true, include the unselected direct dependencies of the selected
+	// packages, so that every edge leaving the subgraph is shown.
+	Leaves bool
+}
+
+// A Subgraph is a selection of nodes and the edges among them.
+type Subgraph struct {
+	Nodes []*Node // ordered by package
+	Edges []Edge  // ordered by source, then target
+}
+
+// A Node is a package in a subgraph. Packages without a row in the graph
+// have only a Package name.
+type Node struct {
+	Package    string
+	Type       string // e.g., "library", "program", "stdlib"
+	Repository string
+	Module     string
+	Ranking    float64
+}
+
+// An Edge is a direct dependency of Source on Target.
+type Edge struct {
+	Source, Target string
+}
+
+// Select constructs the subgraph of g described by opts.
+func Select(ctx context.Context, g *graph.Graph, opts *Options) (*Subgraph, error) {
+	if opts == nil {
+		opts = new(Options)
+	}
+	rows := make(map[string]*graph.Row) // :: package → row, or nil
+	seeds := append([]string(nil), opts.Roots...)
+	if opts.Prefix != "" || len(opts.Roots) == 0 {
+		if err := g.Scan(ctx, opts.Prefix, func(row *graph.Row) error {
+			if !strings.HasPrefix(row.ImportPath, opts.Prefix) {
+				return storage.ErrStopScan
+			}
+			rows[row.ImportPath] = row
+			seeds = append(seeds, row.ImportPath)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	selected := make(map[string]bool)
+	for _, pkg := range seeds {
+		selected[pkg] = true
+	}
+	if opts.MaxDepth > 0 {
+		depth, err := g.Closure(ctx, seeds, &graph.ClosureOptions{MaxDepth: opts.MaxDepth})
+		if err != nil {
+			return nil, err
+		}
+		for pkg := range depth {
+			selected[pkg] = true
+		}
+	}
+	rowOf := func(pkg string) (*graph.Row, error) {
+		if row, ok := rows[pkg]; ok {
+			return row, nil
+		}
+		row, err := g.Row(ctx, pkg)
+		if err == storage.ErrKeyNotFound {
+			err = nil
+		}
+		rows[pkg] = row
+		return row, err
+	}
+
+	sg := new(Subgraph)
+	leaves := make(map[string]bool)
+	for pkg := range selected {
+		row, err := rowOf(pkg)
+		if err != nil {
+			return nil, err
+		} else if row == nil {
+			continue
+		}
+		for _, dep := range row.Directs {
+			if selected[dep] {
+				sg.Edges = append(sg.Edges, Edge{Source: pkg, Target: dep})
+			} else if opts.Leaves {
+				sg.Edges = append(sg.Edges, Edge{Source: pkg, Target: dep})
+				leaves[dep] = true
+			}
+		}
+	}
+	for pkg := range leaves {
+		selected[pkg] = true
+	}
+	for pkg := range selected {
+		row, err := rowOf(pkg)
+		if err != nil {
+			return nil, err
+		}
+		sg.Nodes = append(sg.Nodes, newNode(pkg, row))
+	}
+	sort.Slice(sg.Nodes, func(i, j int) bool { return sg.Nodes[i].Package < sg.Nodes[j].Package })
+	sort.Slice(sg.Edges, func(i, j int) bool {
+		if sg.Edges[i].Source == sg.Edges[j].Source {
+			return sg.Edges[i].Target < sg.Edges[j].Target
+		}
+		return sg.Edges[i].Source < sg.Edges[j].Source
+	})
+	return sg, nil
+}
+
+func newNode(pkg string, row *graph.Row) *Node {
+	node := &Node{Package: pkg}
+	if row != nil {
+		node.Type = strings.ToLower(row.Type.String())
+		node.Repository = row.Repository
+		node.Module = row.Module
+		node.Ranking = row.Ranking
+	}
+	return node
+}
+
+// Formats maps the names of the supported output formats to their writers.
+var Formats = map[string]func(io.Writer, *Subgraph) error{
+	"dot":     WriteDOT,
+	"gexf":    WriteGEXF,
+	"graphml": WriteGraphML,
+}
+
+// Write writes sg to w in the named format.
+func Write(w io.Writer, format string, sg *Subgraph) error {
+	f, ok := Formats[format]
+	if !ok {
+		return fmt.Errorf("unknown format %q", format)
+	}
+	return f(w, sg)
+}
+
+// index returns a map from each package of sg to the position of its node.
+func (sg *Subgraph) index() map[string]int {
+	m := make(map[string]int, len(sg.Nodes))
+	for i, node := range sg.Nodes {
+		m[node.Package] = i
+	}
+	return m
+}
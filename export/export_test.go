@@ -0,0 +1,171 @@
+package export_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/creachadair/ffs/blob/memstore"
+	"github.com/creachadair/repodeps/deps"
+	"github.com/creachadair/repodeps/export"
+	"github.com/creachadair/repodeps/graph"
+	"github.com/creachadair/repodeps/poll"
+	"github.com/creachadair/repodeps/storage"
+
+	_ "modernc.org/sqlite"
+)
+
+func newGraph(t *testing.T) *graph.Graph {
+	t.Helper()
+	g := graph.New(storage.NewBlob(memstore.New()), nil)
+	if err := g.AddAll(context.Background(), &deps.Repo{
+		Remotes: []*deps.Remote{{Name: "origin", Url: "https://github.com/x/y"}},
+		Packages: []*deps.Package{
+			{Name: "main", ImportPath: "x/cmd", Imports: []string{"x/a"}, Type: deps.Package_PROGRAM},
+			{Name: "a", ImportPath: "x/a", Imports: []string{"x/b", "fmt"}},
+			{Name: "b", ImportPath: "x/b", Imports: []string{"y/c"}},
+			{Name: "c", ImportPath: "y/c", Imports: []string{"y/d"}},
+		},
+	}); err != nil {
+		t.Fatalf("AddAll: %v", err)
+	}
+	return g
+}
+
+func TestSelect(t *testing.T) {
+	ctx := context.Background()
+	g := newGraph(t)
+
+	tests := []struct {
+		opts  *export.Options
+		nodes string
+		edges string
+	}{
+		{nil, "x/a x/b x/cmd y/c", "x/a>x/b x/b>y/c x/cmd>x/a"},
+		{&export.Options{Prefix: "x/"}, "x/a x/b x/cmd", "x/a>x/b x/cmd>x/a"},
+		{&export.Options{Prefix: "x/", Leaves: true}, "fmt x/a x/b x/cmd y/c",
+			"x/a>fmt x/a>x/b x/b>y/c x/cmd>x/a"},
+		{&export.Options{Roots: []string{"x/cmd"}, MaxDepth: 2}, "fmt x/a x/b x/cmd", "x/a>fmt x/a>x/b x/cmd>x/a"},
+		{&export.Options{Prefix: "y/", MaxDepth: 1}, "y/c y/d", "y/c>y/d"},
+	}
+	for _, test := range tests {
+		sg, err := export.Select(ctx, g, test.opts)
+		if err != nil {
+			t.Errorf("Select(%+v) failed: %v", test.opts, err)
+			continue
+		}
+		var nodes, edges []string
+		for _, n := range sg.Nodes {
+			nodes = append(nodes, n.Package)
+		}
+		for _, e := range sg.Edges {
+			edges = append(edges, e.Source+">"+e.Target)
+		}
+		if got := strings.Join(nodes, " "); got != test.nodes {
+			t.Errorf("Select(%+v) nodes: got %q, want %q", test.opts, got, test.nodes)
+		}
+		if got := strings.Join(edges, " "); got != test.edges {
+			t.Errorf("Select(%+v) edges: got %q, want %q", test.opts, got, test.edges)
+		}
+	}
+}
+
+func TestFormats(t *testing.T) {
+	sg, err := export.Select(context.Background(), newGraph(t), &export.Options{Prefix: "x/", Leaves: true})
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+	for name := range export.Formats {
+		var buf bytes.Buffer
+		if err := export.Write(&buf, name, sg); err != nil {
+			t.Errorf("Write %s failed: %v", name, err)
+			continue
+		}
+		out := buf.String()
+		t.Logf("Output for %s:\n%s", name, out)
+		for _, want := range []string{"x/cmd", "https://github.com/x/y", "program"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("Write %s: output does not contain %q", name, want)
+			}
+		}
+		if name != "dot" {
+			if err := xml.Unmarshal(buf.Bytes(), new(struct{})); err != nil {
+				t.Errorf("Write %s: invalid XML: %v", name, err)
+			}
+		}
+	}
+	if err := export.Write(new(bytes.Buffer), "bogus", sg); err == nil {
+		t.Error("Write bogus: got nil error, want error")
+	}
+}
+
+func TestWriteSQLite(t *testing.T) {
+	ctx := context.Background()
+	g := newGraph(t)
+	repos := poll.NewDB(storage.NewBlob(memstore.New()))
+	if err := repos.Put(ctx, &poll.Status{Repository: "https://github.com/x/y", Prefix: "x"}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	// A row whose repository URL is not in canonical form.
+	if err := g.AddAll(ctx, &deps.Repo{
+		Remotes:  []*deps.Remote{{Name: "origin", Url: "https://github.com/Z/W.git"}},
+		Packages: []*deps.Package{{Name: "w", ImportPath: "github.com/Z/W"}},
+	}); err != nil {
+		t.Fatalf("AddAll: %v", err)
+	}
+	if err := repos.Put(ctx, &poll.Status{Repository: "https://github.com/z/w"}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := repos.AddAlias(ctx, "https://github.com/x/old", "https://github.com/x/y"); err != nil {
+		t.Fatalf("AddAlias: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "deps.db"))
+	if err != nil {
+		t.Fatalf("Opening database: %v", err)
+	}
+	defer db.Close()
+	if err := export.WriteSQLite(ctx, db, g, repos); err != nil {
+		t.Fatalf("WriteSQLite failed: %v", err)
+	}
+	if err := export.WriteSQLite(ctx, db, g, repos); err == nil {
+		t.Error("WriteSQLite over existing tables: got nil error, want error")
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`SELECT count(*) FROM packages WHERE indexed = 1`, "5"},
+		{`SELECT group_concat(import_path, ' ') FROM (
+		    SELECT import_path FROM packages WHERE indexed = 0 ORDER BY import_path)`, "fmt y/d"},
+		{`SELECT group_concat(p.import_path, ' ') FROM (
+		    SELECT d.import_path FROM imports i
+		      JOIN packages s ON s.id = i.package_id
+		      JOIN packages d ON d.id = i.import_id
+		     WHERE s.import_path = 'x/a' ORDER BY d.import_path) p`, "fmt x/b"},
+		{`SELECT type FROM packages WHERE import_path = 'x/cmd'`, "program"},
+		{`SELECT group_concat(url, ' ') FROM (
+		    SELECT r.url FROM statuses s JOIN repositories r ON r.id = s.repository_id ORDER BY r.url)`,
+			"https://github.com/x/y https://github.com/z/w"},
+		{`SELECT p.repository_url || ' ' || r.url FROM packages p
+		    JOIN statuses s ON s.repository_id = p.repository_id
+		    JOIN repositories r ON r.id = p.repository_id
+		   WHERE p.import_path = 'github.com/Z/W'`, "https://github.com/Z/W.git https://github.com/z/w"},
+		{`SELECT r.url FROM aliases a JOIN repositories r ON r.id = a.repository_id
+		   WHERE a.alias = 'https://github.com/x/old'`, "https://github.com/x/y"},
+	}
+	for _, test := range tests {
+		var got string
+		if err := db.QueryRowContext(ctx, test.query).Scan(&got); err != nil {
+			t.Errorf("Query %q failed: %v", test.query, err)
+		} else if got != test.want {
+			t.Errorf("Query %q: got %q, want %q", test.query, got, test.want)
+		}
+	}
+}
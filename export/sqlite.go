@@ -0,0 +1,287 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/creachadair/repodeps/graph"
+	"github.com/creachadair/repodeps/poll"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// sqliteSchema defines the tables written by WriteSQLite. Timestamps are
+// stored as RFC 3339 strings and digests as hex strings. Repositories are
+// identified by their canonical URLs (see poll.CanonicalURL), as statuses and
+// aliases are, and each package also records the repository URL of its row.
+// Foreign keys are declared for documentation; WriteSQLite does not require
+// SQLite to enforce them.
+var sqliteSchema = []string{
+	`CREATE TABLE repositories (
+  id  INTEGER PRIMARY KEY,
+  url TEXT NOT NULL UNIQUE
+)`,
+	`CREATE TABLE packages (
+  id              INTEGER PRIMARY KEY,
+  import_path     TEXT NOT NULL UNIQUE,
+  indexed         INTEGER NOT NULL, -- 0 if the package has no row in the graph
+  name            TEXT,
+  type            TEXT,
+  repository_id   INTEGER REFERENCES repositories(id),
+  repository_url  TEXT,    -- the repository URL as recorded in the row
+  module          TEXT,
+  ranking         REAL,
+  dependents      INTEGER,
+  dependent_repos INTEGER
+)`,
+	`CREATE TABLE imports (
+  package_id INTEGER NOT NULL REFERENCES packages(id),
+  import_id  INTEGER NOT NULL REFERENCES packages(id),
+  PRIMARY KEY (package_id, import_id)
+) WITHOUT ROWID`,
+	`CREATE TABLE files (
+  package_id INTEGER NOT NULL REFERENCES packages(id),
+  repo_path  TEXT NOT NULL,
+  digest     TEXT NOT NULL,
+  PRIMARY KEY (package_id, repo_path)
+) WITHOUT ROWID`,
+	`CREATE TABLE statuses (
+  repository_id INTEGER NOT NULL REFERENCES repositories(id),
+  tag           TEXT NOT NULL,
+  ref_name      TEXT,
+  digest        TEXT,
+  error_count   INTEGER,
+  prefix        TEXT,
+  last_check    TEXT,
+  PRIMARY KEY (repository_id, tag)
+) WITHOUT ROWID`,
+	`CREATE TABLE status_updates (
+  repository_id INTEGER NOT NULL REFERENCES repositories(id),
+  tag           TEXT NOT NULL,
+  time          TEXT,
+  digest        TEXT
+)`,
+	`CREATE TABLE aliases (
+  alias         TEXT PRIMARY KEY,
+  repository_id INTEGER NOT NULL REFERENCES repositories(id)
+)`,
+	`CREATE INDEX packages_repository ON packages (repository_id)`,
+	`CREATE INDEX packages_module ON packages (module)`,
+	`CREATE INDEX imports_import ON imports (import_id)`,
+	`CREATE INDEX files_digest ON files (digest)`,
+	`CREATE INDEX status_updates_repository ON status_updates (repository_id, tag)`,
+}
+
+// WriteSQLite writes the rows of g, and the statuses and aliases of repos if
+// it is not nil, into new tables of the SQLite database db. The tables must
+// not already exist. Every dependency of a row is recorded in the packages
+// table, with indexed = 0 if it has no row of its own.
+//
+// The database is written in a single transaction, so if WriteSQLite fails
+// the database is not modified.
+func WriteSQLite(ctx context.Context, db *sql.DB, g *graph.Graph, repos *poll.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	w := &sqlWriter{
+		ctx:   ctx,
+		tx:    tx,
+		pkgs:  make(map[string]int64),
+		repos: make(map[string]int64),
+	}
+	if err := w.write(g, repos); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// A sqlWriter holds the state of a call to WriteSQLite.
+type sqlWriter struct {
+	ctx   context.Context
+	tx    *sql.Tx
+	pkgs  map[string]int64 // :: import path → package ID
+	repos map[string]int64 // :: canonical repository URL → repository ID
+}
+
+func (w *sqlWriter) write(g *graph.Graph, repos *poll.DB) error {
+	for _, stmt := range sqliteSchema {
+		if _, err := w.tx.ExecContext(w.ctx, stmt); err != nil {
+			return fmt.Errorf("creating schema: %v", err)
+		}
+	}
+
+	// Pass 1: Record each row, so that packages with rows have the smallest
+	// IDs and dependencies can be resolved in one pass.
+	insPkg, err := w.tx.PrepareContext(w.ctx, `INSERT INTO packages
+  (id, import_path, indexed, name, type, repository_id, repository_url, module, ranking, dependents, dependent_repos)
+  VALUES (?, ?, 1, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	insFile, err := w.tx.PrepareContext(w.ctx, `INSERT INTO files (package_id, repo_path, digest) VALUES (?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	if err := g.Scan(w.ctx, "", func(row *graph.Row) error {
+		rid, err := w.repoID(row.Repository)
+		if err != nil {
+			return err
+		}
+		id := int64(len(w.pkgs) + 1)
+		w.pkgs[row.ImportPath] = id
+		if _, err := insPkg.ExecContext(w.ctx, id, row.ImportPath, row.Name,
+			strings.ToLower(row.Type.String()), rid, nullString(row.Repository), nullString(row.Module),
+			row.Ranking, row.Dependents, row.DependentRepos); err != nil {
+			return fmt.Errorf("package %q: %v", row.ImportPath, err)
+		}
+		for _, file := range row.SourceFiles {
+			if _, err := insFile.ExecContext(w.ctx, id, file.RepoPath, hex.EncodeToString(file.Digest)); err != nil {
+				return fmt.Errorf("package %q file %q: %v", row.ImportPath, file.RepoPath, err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// Pass 2: Record the imports of each row, adding unindexed packages for
+	// dependencies that do not have rows.
+	insDep, err := w.tx.PrepareContext(w.ctx, `INSERT OR IGNORE INTO imports (package_id, import_id) VALUES (?, ?)`)
+	if err != nil {
+		return err
+	}
+	if err := g.Scan(w.ctx, "", func(row *graph.Row) error {
+		src := w.pkgs[row.ImportPath]
+		for _, dep := range row.Directs {
+			dst, err := w.packageID(dep)
+			if err != nil {
+				return err
+			}
+			if _, err := insDep.ExecContext(w.ctx, src, dst); err != nil {
+				return fmt.Errorf("import %q → %q: %v", row.ImportPath, dep, err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if repos != nil {
+		return w.writeRepos(repos)
+	}
+	return nil
+}
+
+// writeRepos records the statuses and aliases of repos.
+func (w *sqlWriter) writeRepos(repos *poll.DB) error {
+	insStat, err := w.tx.PrepareContext(w.ctx, `INSERT INTO statuses
+  (repository_id, tag, ref_name, digest, error_count, prefix, last_check)
+  VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	insUpdate, err := w.tx.PrepareContext(w.ctx, `INSERT INTO status_updates (repository_id, tag, time, digest) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	if err := repos.ScanStatus(w.ctx, "", func(stat *poll.Status) error {
+		rid, err := w.repoID(stat.Repository)
+		if err != nil {
+			return err
+		}
+		if _, err := insStat.ExecContext(w.ctx, rid, stat.Tag, stat.RefName,
+			nullString(hex.EncodeToString(stat.Digest)), stat.ErrorCount,
+			nullString(stat.Prefix), timeString(stat.LastCheck)); err != nil {
+			return fmt.Errorf("status %q: %v", stat.Repository, err)
+		}
+		for _, up := range stat.Updates {
+			if _, err := insUpdate.ExecContext(w.ctx, rid, stat.Tag, timeString(up.When),
+				hex.EncodeToString(up.Digest)); err != nil {
+				return fmt.Errorf("status %q update: %v", stat.Repository, err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	insAlias, err := w.tx.PrepareContext(w.ctx, `INSERT INTO aliases (alias, repository_id) VALUES (?, ?)`)
+	if err != nil {
+		return err
+	}
+	return repos.Aliases(w.ctx, func(alias *poll.Alias) error {
+		rid, err := w.repoID(alias.Repository)
+		if err != nil {
+			return err
+		}
+		if _, err := insAlias.ExecContext(w.ctx, alias.Alias, rid); err != nil {
+			return fmt.Errorf("alias %q: %v", alias.Alias, err)
+		}
+		return nil
+	})
+}
+
+// repoID returns the ID of the repository with the given URL, adding it to
+// the repositories table if necessary. Repositories are identified by their
+// canonical URLs.
+func (w *sqlWriter) repoID(url string) (int64, error) {
+	url = poll.CanonicalURL(url)
+	if id, ok := w.repos[url]; ok {
+		return id, nil
+	}
+	id := int64(len(w.repos) + 1)
+	if _, err := w.tx.ExecContext(w.ctx, `INSERT INTO repositories (id, url) VALUES (?, ?)`, id, url); err != nil {
+		return 0, fmt.Errorf("repository %q: %v", url, err)
+	}
+	w.repos[url] = id
+	return id, nil
+}
+
+// packageID returns the ID of the package with the given import path, adding
+// it to the packages table as unindexed if necessary.
+func (w *sqlWriter) packageID(pkg string) (int64, error) {
+	if id, ok := w.pkgs[pkg]; ok {
+		return id, nil
+	}
+	id := int64(len(w.pkgs) + 1)
+	if _, err := w.tx.ExecContext(w.ctx, `INSERT INTO packages (id, import_path, indexed) VALUES (?, ?, 0)`, id, pkg); err != nil {
+		return 0, fmt.Errorf("package %q: %v", pkg, err)
+	}
+	w.pkgs[pkg] = id
+	return id, nil
+}
+
+// nullString returns nil for an empty string, so that it is stored as NULL.
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// timeString formats ts as an RFC 3339 string, or returns nil if ts is unset.
+func timeString(ts *timestamppb.Timestamp) interface{} {
+	if ts == nil {
+		return nil
+	}
+	return ts.AsTime().UTC().Format(time.RFC3339Nano)
+}
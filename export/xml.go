@@ -0,0 +1,193 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// nodeAttrs lists the attributes of a node, with their GraphML/GEXF types.
+var nodeAttrs = []struct{ name, kind string }{
+	{"label", "string"},
+	{"type", "string"},
+	{"repository", "string"},
+	{"module", "string"},
+	{"ranking", "double"},
+}
+
+// values returns the values of the attributes of n in the order of nodeAttrs,
+// with empty strings for unset attributes.
+func (n *Node) values() []string {
+	var rank string
+	if n.Ranking != 0 {
+		rank = strconv.FormatFloat(n.Ranking, 'g', -1, 64)
+	}
+	return []string{n.Package, n.Type, n.Repository, n.Module, rank}
+}
+
+// writeXML writes v to w as an indented XML document.
+func writeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteGraphML writes sg to w as a GraphML document. Nodes are identified by
+// their position in sg.Nodes, and the package name is given by the "label"
+// attribute.
+//
+// See http://graphml.graphdrawing.org/specification.html
+func WriteGraphML(w io.Writer, sg *Subgraph) error {
+	type data struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:",chardata"`
+	}
+	type node struct {
+		ID   string `xml:"id,attr"`
+		Data []data `xml:"data"`
+	}
+	type edge struct {
+		Source string `xml:"source,attr"`
+		Target string `xml:"target,attr"`
+	}
+	type key struct {
+		ID   string `xml:"id,attr"`
+		For  string `xml:"for,attr"`
+		Name string `xml:"attr.name,attr"`
+		Type string `xml:"attr.type,attr"`
+	}
+	type doc struct {
+		XMLName xml.Name `xml:"http://graphml.graphdrawing.org/xmlns graphml"`
+		Keys    []key    `xml:"key"`
+		Graph   struct {
+			ID          string `xml:"id,attr"`
+			EdgeDefault string `xml:"edgedefault,attr"`
+			Nodes       []node `xml:"node"`
+			Edges       []edge `xml:"edge"`
+		} `xml:"graph"`
+	}
+
+	var d doc
+	for _, attr := range nodeAttrs {
+		d.Keys = append(d.Keys, key{ID: attr.name, For: "node", Name: attr.name, Type: attr.kind})
+	}
+	d.Graph.ID = "deps"
+	d.Graph.EdgeDefault = "directed"
+	for i, n := range sg.Nodes {
+		out := node{ID: nodeID(i)}
+		for j, v := range n.values() {
+			if v != "" {
+				out.Data = append(out.Data, data{Key: nodeAttrs[j].name, Value: v})
+			}
+		}
+		d.Graph.Nodes = append(d.Graph.Nodes, out)
+	}
+	idx := sg.index()
+	for _, e := range sg.Edges {
+		d.Graph.Edges = append(d.Graph.Edges, edge{
+			Source: nodeID(idx[e.Source]),
+			Target: nodeID(idx[e.Target]),
+		})
+	}
+	return writeXML(w, d)
+}
+
+// WriteGEXF writes sg to w as a GEXF 1.3 document. The package name is the
+// label of each node, and the other node attributes are declared as GEXF
+// attributes.
+//
+// See https://gexf.net/schema.html
+func WriteGEXF(w io.Writer, sg *Subgraph) error {
+	type attribute struct {
+		ID    string `xml:"id,attr"`
+		Title string `xml:"title,attr"`
+		Type  string `xml:"type,attr"`
+	}
+	type attvalue struct {
+		For   string `xml:"for,attr"`
+		Value string `xml:"value,attr"`
+	}
+	type attvalues struct {
+		Values []attvalue `xml:"attvalue"`
+	}
+	type node struct {
+		ID     string     `xml:"id,attr"`
+		Label  string     `xml:"label,attr"`
+		Values *attvalues `xml:"attvalues,omitempty"`
+	}
+	type edge struct {
+		ID     string `xml:"id,attr"`
+		Source string `xml:"source,attr"`
+		Target string `xml:"target,attr"`
+	}
+	type attributes struct {
+		Class      string      `xml:"class,attr"`
+		Attributes []attribute `xml:"attribute"`
+	}
+	type doc struct {
+		XMLName xml.Name `xml:"http://gexf.net/1.3 gexf"`
+		Version string   `xml:"version,attr"`
+		Graph   struct {
+			DefaultEdgeType string     `xml:"defaultedgetype,attr"`
+			Mode            string     `xml:"mode,attr"`
+			Attributes      attributes `xml:"attributes"`
+			Nodes           []node     `xml:"nodes>node"`
+			Edges           []edge     `xml:"edges>edge"`
+		} `xml:"graph"`
+	}
+
+	d := doc{Version: "1.3"}
+	d.Graph.DefaultEdgeType = "directed"
+	d.Graph.Mode = "static"
+	d.Graph.Attributes.Class = "node"
+	attrs := nodeAttrs[1:] // the label is not an attribute in GEXF
+	for _, attr := range attrs {
+		d.Graph.Attributes.Attributes = append(d.Graph.Attributes.Attributes, attribute{ID: attr.name, Title: attr.name, Type: attr.kind})
+	}
+	for i, n := range sg.Nodes {
+		vals := n.values()
+		out := node{ID: nodeID(i), Label: vals[0]}
+		for j, v := range vals[1:] {
+			if v == "" {
+				continue
+			} else if out.Values == nil {
+				out.Values = new(attvalues)
+			}
+			out.Values.Values = append(out.Values.Values, attvalue{For: attrs[j].name, Value: v})
+		}
+		d.Graph.Nodes = append(d.Graph.Nodes, out)
+	}
+	idx := sg.index()
+	for i, e := range sg.Edges {
+		d.Graph.Edges = append(d.Graph.Edges, edge{
+			ID:     fmt.Sprint(i),
+			Source: nodeID(idx[e.Source]),
+			Target: nodeID(idx[e.Target]),
+		})
+	}
+	return writeXML(w, d)
+}
+
+func nodeID(i int) string { return "n" + strconv.Itoa(i) }
@@ -0,0 +1,136 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// A Level selects how Aggregate groups the packages of a graph.
+type Level int
+
+// The supported aggregation levels.
+const (
+	ByRepository Level = iota // group packages by repository URL
+	ByModule                  // group packages by module path
+)
+
+var levelName = map[Level]string{ByRepository: "repository", ByModule: "module"}
+
+func (v Level) String() string {
+	if s, ok := levelName[v]; ok {
+		return s
+	}
+	return fmt.Sprintf("Level(%d)", int(v))
+}
+
+// ParseLevel parses the name of an aggregation level, either "repository" or
+// "module".
+func ParseLevel(s string) (Level, error) {
+	for v, name := range levelName {
+		if s == name {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown aggregation level %q", s)
+}
+
+// group returns the name of the group containing row at this level, or ""
+// if the row does not belong to any group.
+func (v Level) group(row *Row) string {
+	switch v {
+	case ByRepository:
+		return row.Repository
+	case ByModule:
+		return row.Module
+	}
+	return ""
+}
+
+// A Node is a node of an aggregated graph, representing a group of packages.
+type Node struct {
+	Name     string  `json:"name"`            // repository URL or module path
+	Packages int     `json:"packages"`        // number of packages in the group
+	Edges    []*Edge `json:"edges,omitempty"` // outbound edges, ordered by target
+}
+
+// An Edge is a weighted edge of an aggregated graph.
+type Edge struct {
+	Target string `json:"target"` // name of the target node
+	Weight int    `json:"weight"` // number of package-level edges
+}
+
+// Aggregate collapses the rows of g into groups at the specified level, and
+// returns a graph whose nodes are the groups. There is an edge from group A
+// to group B if some package in A directly depends on some package in B,
+// weighted by the number of such package-level edges. Dependencies within a
+// group, dependencies on packages without a row, and rows not belonging to
+// any group (for example, rows without a module path) are omitted.
+//
+// The resulting nodes are ordered by name.
+func (g *Graph) Aggregate(ctx context.Context, level Level) ([]*Node, error) {
+	groupOf, directs, err := g.groups(ctx, level)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]*Node)
+	weights := make(map[string]map[string]int) // :: src group → dst group → weight
+	for pkg, src := range groupOf {
+		n, ok := nodes[src]
+		if !ok {
+			n = &Node{Name: src}
+			nodes[src] = n
+			weights[src] = make(map[string]int)
+		}
+		n.Packages++
+		for _, dep := range directs[pkg] {
+			if dst, ok := groupOf[dep]; ok && dst != src {
+				weights[src][dst]++
+			}
+		}
+	}
+
+	out := make([]*Node, 0, len(nodes))
+	for name, n := range nodes {
+		for dst, w := range weights[name] {
+			n.Edges = append(n.Edges, &Edge{Target: dst, Weight: w})
+		}
+		sort.Slice(n.Edges, func(i, j int) bool {
+			return n.Edges[i].Target < n.Edges[j].Target
+		})
+		out = append(out, n)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// groups returns the name of the group containing each package of g at the
+// specified level, and the direct dependencies of each such package. Rows not
+// belonging to any group are omitted.
+func (g *Graph) groups(ctx context.Context, level Level) (groupOf map[string]string, directs map[string][]string, err error) {
+	groupOf = make(map[string]string) // :: import path → group name
+	directs = make(map[string][]string)
+	err = g.Scan(ctx, "", func(row *Row) error {
+		if name := level.group(row); name != "" {
+			groupOf[row.ImportPath] = name
+			directs[row.ImportPath] = row.Directs
+		}
+		return nil
+	})
+	return
+}
@@ -0,0 +1,159 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"context"
+	"strings"
+
+	"github.com/creachadair/repodeps/storage"
+)
+
+// The kinds of problem reported by Check.
+const (
+	BadRow      = "badRow"      // a row that cannot be decoded
+	KeyMismatch = "keyMismatch" // a row stored under a key other than its import path
+	BadRepo     = "badRepo"     // a repository record that cannot be decoded
+	EmptyRepo   = "emptyRepo"   // a repository record with no rows
+	BadVersion  = "badVersion"  // a history record that cannot be decoded
+)
+
+// A Problem describes an inconsistency found by Check.
+type Problem struct {
+	Kind     string `json:"kind"`
+	Key      string `json:"key"` // the affected key, relative to the view
+	Detail   string `json:"detail,omitempty"`
+	Repaired bool   `json:"repaired,omitempty"`
+}
+
+// Check scans the records of g for inconsistencies, and calls f for each
+// problem found. If repair(kind) reports true, Check attempts to repair
+// problems of that kind before calling f, and marks those it repaired:
+//
+//	BadRow, BadRepo, BadVersion: the record is deleted.
+//	KeyMismatch: the row is moved to its import path, unless a row already
+//	   exists there, in which case the misplaced row is deleted.
+//	EmptyRepo: the repository record is deleted.
+//
+// A nil repair function repairs nothing. If f reports an error, checking
+// terminates and that error is returned to the caller of Check. Check does
+// not examine other tagged views of the graph.
+func (g *Graph) Check(ctx context.Context, repair func(kind string) bool, f func(*Problem) error) error {
+	if repair == nil {
+		repair = func(string) bool { return false }
+	}
+	report := func(kind, key, detail string, fix func() error) error {
+		p := &Problem{Kind: kind, Key: key, Detail: detail}
+		if repair(kind) {
+			if err := fix(); err != nil {
+				p.Detail += "; repair failed: " + err.Error()
+			} else {
+				p.Repaired = true
+			}
+		}
+		return f(p)
+	}
+
+	// Check the rows, and record which repositories have rows.
+	repos := make(map[string]bool)
+	if err := g.List(ctx, "", func(key string) error {
+		var row Row
+		if err := g.st.Load(ctx, g.key(key), &row); err != nil {
+			return report(BadRow, key, err.Error(), func() error {
+				return g.st.Delete(ctx, g.key(key))
+			})
+		}
+		repos[row.Repository] = true
+		if row.ImportPath == key {
+			return nil
+		}
+		return report(KeyMismatch, key, "import path is "+row.ImportPath, func() error {
+			var old Row
+			if err := g.st.Load(ctx, g.key(row.ImportPath), &old); err == storage.ErrKeyNotFound {
+				if err := g.storeRow(ctx, &row); err != nil {
+					return err
+				}
+			}
+			return g.st.Delete(ctx, g.key(key))
+		})
+	}); err != nil {
+		return err
+	}
+
+	// Check the repository records.
+	pfx := g.key(auxKey(repoKind, ""))
+	if err := g.st.Scan(ctx, pfx, func(key string) error {
+		if !strings.HasPrefix(key, pfx) {
+			return storage.ErrStopScan
+		}
+		name := strings.TrimPrefix(key, g.pfx)
+		var rec Repo
+		if err := g.st.Load(ctx, key, &rec); err != nil {
+			return report(BadRepo, name, err.Error(), func() error { return g.st.Delete(ctx, key) })
+		} else if !repos[rec.Repository] {
+			return report(EmptyRepo, name, rec.Repository, func() error { return g.st.Delete(ctx, key) })
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// Check the history records.
+	pfx = g.key(auxKey(histKind, ""))
+	return g.st.Scan(ctx, pfx, func(key string) error {
+		if !strings.HasPrefix(key, pfx) {
+			return storage.ErrStopScan
+		}
+		name := strings.TrimPrefix(key, g.pfx)
+		var v Version
+		if _, _, _, ok := parseHistKey(strings.TrimPrefix(key, pfx)); !ok {
+			return report(BadVersion, name, "malformed key", func() error { return g.st.Delete(ctx, key) })
+		} else if err := g.st.Load(ctx, key, &v); err != nil {
+			return report(BadVersion, name, err.Error(), func() error { return g.st.Delete(ctx, key) })
+		}
+		return nil
+	})
+}
+
+// Tags calls f with the name of each tag that has records in the graph, in
+// lexicographic order. If f reports an error, scanning terminates. If the
+// error is storage.ErrStopScan, Tags returns nil. Otherwise Tags returns the
+// error from f.
+func (g *Graph) Tags(ctx context.Context, f func(tag string) error) error {
+	pfx := auxKey(tagKind, "")
+	start := pfx
+	for {
+		var tag string
+		if err := g.st.Scan(ctx, start, func(key string) error {
+			if strings.HasPrefix(key, pfx) {
+				rest := strings.TrimPrefix(key, pfx)
+				if i := strings.Index(rest, "\x00"); i >= 0 {
+					tag = rest[:i]
+				}
+			}
+			return storage.ErrStopScan
+		}); err != nil {
+			return err
+		} else if tag == "" {
+			return nil // no more tags
+		}
+		if err := f(tag); err == storage.ErrStopScan {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		start = pfx + tag + "\x01" // skip the remaining keys of this tag
+	}
+}
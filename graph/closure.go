@@ -0,0 +1,114 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"context"
+
+	"github.com/creachadair/repodeps/storage"
+)
+
+// ClosureOptions control the traversal performed by Closure. A nil
+// *ClosureOptions provides default values.
+type ClosureOptions struct {
+	// If true, follow dependencies in reverse, from imported packages to
+	// their importers.
+	Reverse bool
+
+	// If positive, do not traverse more than this many edges from a root.
+	MaxDepth int
+
+	// If set, neither report nor traverse any package for which Skip reports
+	// true. The row is nil if the package has no row in the graph. The roots
+	// are never skipped.
+	Skip func(pkg string, row *Row) bool
+}
+
+// Closure computes the transitive closure of the dependencies of the root
+// packages, and returns the depth of each package reached, that is, the
+// length of the shortest chain of imports from some root. The roots
+// themselves are reported at depth 0. Packages without a row in the graph are
+// reported if reached, but have no dependencies of their own.
+//
+// A reverse closure requires a complete scan of the graph.
+func (g *Graph) Closure(ctx context.Context, roots []string, opts *ClosureOptions) (map[string]int, error) {
+	if opts == nil {
+		opts = new(ClosureOptions)
+	}
+	rowOf := func(pkg string) (*Row, error) {
+		row, err := g.Row(ctx, pkg)
+		if err == storage.ErrKeyNotFound {
+			return nil, nil
+		}
+		return row, err
+	}
+	next := func(pkg string) ([]string, error) {
+		row, err := rowOf(pkg)
+		if row == nil {
+			return nil, err
+		}
+		return row.Directs, nil
+	}
+	if opts.Reverse {
+		rev := make(map[string][]string) // :: package → importers
+		if err := g.Scan(ctx, "", func(row *Row) error {
+			for _, dep := range row.Directs {
+				rev[dep] = append(rev[dep], row.ImportPath)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		next = func(pkg string) ([]string, error) { return rev[pkg], nil }
+	}
+
+	depth := make(map[string]int)
+	var queue []string
+	for _, root := range roots {
+		if _, ok := depth[root]; !ok {
+			depth[root] = 0
+			queue = append(queue, root)
+		}
+	}
+	skipped := make(map[string]bool)
+	for len(queue) != 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		d := depth[cur]
+		if opts.MaxDepth > 0 && d >= opts.MaxDepth {
+			continue
+		}
+		succ, err := next(cur)
+		if err != nil {
+			return nil, err
+		}
+		for _, pkg := range succ {
+			if _, ok := depth[pkg]; ok || skipped[pkg] {
+				continue
+			} else if opts.Skip != nil {
+				row, err := rowOf(pkg)
+				if err != nil {
+					return nil, err
+				} else if opts.Skip(pkg, row) {
+					skipped[pkg] = true
+					continue
+				}
+			}
+			depth[pkg] = d + 1
+			queue = append(queue, pkg)
+		}
+	}
+	return depth, nil
+}
@@ -0,0 +1,202 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"context"
+	"sort"
+)
+
+// A Cycle is a strongly-connected component of an aggregated graph having
+// more than one node. Every node of a cycle can reach every other.
+type Cycle struct {
+	Nodes []string       `json:"nodes"`           // group names, in order
+	Edges []*PackageEdge `json:"edges,omitempty"` // package edges within the cycle
+}
+
+// A PackageEdge is a package-level dependency that contributes to an edge of
+// an aggregated graph.
+type PackageEdge struct {
+	Source string `json:"source"` // the importing package
+	Target string `json:"target"` // the imported package
+	From   string `json:"from"`   // the group containing source
+	To     string `json:"to"`     // the group containing target
+}
+
+// Cycles finds the strongly-connected components of the graph aggregated at
+// the specified level (see Aggregate), and reports each component with more
+// than one node, along with the package-level edges that connect the distinct
+// groups of the component. Cycles are ordered by their first node, and the
+// edges of each cycle by source and target.
+func (g *Graph) Cycles(ctx context.Context, level Level) ([]*Cycle, error) {
+	groupOf, directs, err := g.groups(ctx, level)
+	if err != nil {
+		return nil, err
+	}
+	adj := make(map[string]map[string]bool) // :: src group → set of dst groups
+	for pkg, src := range groupOf {
+		if adj[src] == nil {
+			adj[src] = make(map[string]bool)
+		}
+		for _, dep := range directs[pkg] {
+			if dst, ok := groupOf[dep]; ok && dst != src {
+				adj[src][dst] = true
+			}
+		}
+	}
+
+	compOf := make(map[string]*Cycle) // :: group name → its cycle
+	var cycles []*Cycle
+	for _, comp := range components(adj) {
+		if len(comp) < 2 {
+			continue
+		}
+		sort.Strings(comp)
+		c := &Cycle{Nodes: comp}
+		for _, name := range comp {
+			compOf[name] = c
+		}
+		cycles = append(cycles, c)
+	}
+	for pkg, src := range groupOf {
+		c := compOf[src]
+		if c == nil {
+			continue
+		}
+		for _, dep := range directs[pkg] {
+			if dst, ok := groupOf[dep]; ok && dst != src && compOf[dst] == c {
+				c.Edges = append(c.Edges, &PackageEdge{Source: pkg, Target: dep, From: src, To: dst})
+			}
+		}
+	}
+	for _, c := range cycles {
+		sort.Slice(c.Edges, func(i, j int) bool {
+			if c.Edges[i].Source == c.Edges[j].Source {
+				return c.Edges[i].Target < c.Edges[j].Target
+			}
+			return c.Edges[i].Source < c.Edges[j].Source
+		})
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i].Nodes[0] < cycles[j].Nodes[0] })
+	return cycles, nil
+}
+
+// components returns the strongly-connected components of the directed graph
+// with adjacency sets adj, using Tarjan's algorithm.
+func components(adj map[string]map[string]bool) [][]string {
+	var names []string
+	id := make(map[string]int)
+	for v := range adj {
+		id[v] = len(names)
+		names = append(names, v)
+	}
+	succ := make([][]int, len(names))
+	for v, ws := range adj {
+		for w := range ws {
+			if _, ok := id[w]; !ok {
+				id[w] = len(names)
+				names = append(names, w)
+				succ = append(succ, nil)
+			}
+			succ[id[v]] = append(succ[id[v]], id[w])
+		}
+	}
+	var out [][]string
+	for _, comp := range StrongComponents(len(names), func(v int) []int { return succ[v] }) {
+		elts := make([]string, len(comp))
+		for i, v := range comp {
+			elts[i] = names[v]
+		}
+		out = append(out, elts)
+	}
+	return out
+}
+
+// StrongComponents returns the strongly-connected components of the directed
+// graph whose nodes are the integers 0..n-1, where succ(v) returns the
+// successors of node v. Each component is reported after every component
+// reachable from it, so the result is in reverse topological order of the
+// condensed graph. This uses Tarjan's algorithm, without recursion, so that
+// very deep graphs do not exhaust the stack.
+func StrongComponents(n int, succ func(int) []int) [][]int {
+	const unvisited = -1
+	index := make([]int, n)
+	low := make([]int, n)
+	onStack := make([]bool, n)
+	for i := range index {
+		index[i] = unvisited
+	}
+	type frame struct {
+		v    int
+		next []int // successors of v not yet visited
+	}
+	var (
+		stack []int // Tarjan's component stack
+		calls []frame
+		out   [][]int
+		count int
+	)
+	push := func(v int) {
+		index[v], low[v] = count, count
+		count++
+		stack = append(stack, v)
+		onStack[v] = true
+		calls = append(calls, frame{v: v, next: succ(v)})
+	}
+	for root := 0; root < n; root++ {
+		if index[root] != unvisited {
+			continue
+		}
+		push(root)
+		for len(calls) != 0 {
+			top := &calls[len(calls)-1]
+			v := top.v
+			if len(top.next) != 0 {
+				w := top.next[0]
+				top.next = top.next[1:]
+				if index[w] == unvisited {
+					push(w)
+				} else if onStack[w] && index[w] < low[v] {
+					low[v] = index[w]
+				}
+				continue
+			}
+
+			// All the successors of v are done; return to the caller.
+			calls = calls[:len(calls)-1]
+			if len(calls) != 0 {
+				if u := calls[len(calls)-1].v; low[v] < low[u] {
+					low[u] = low[v]
+				}
+			}
+			if low[v] != index[v] {
+				continue // v is not the root of a component
+			}
+			var comp []int
+			for {
+				k := len(stack) - 1
+				w := stack[k]
+				stack = stack[:k]
+				onStack[w] = false
+				comp = append(comp, w)
+				if w == v {
+					break
+				}
+			}
+			out = append(out, comp)
+		}
+	}
+	return out
+}
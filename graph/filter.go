@@ -0,0 +1,228 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A Filter is a compiled query that selects rows of the graph.
+type Filter struct {
+	terms  []func(*Row) bool
+	prefix string
+}
+
+// ParseFilter parses a query string into a Filter. A query is a sequence of
+// terms separated by whitespace, all of which must match for a row to match.
+// A term prefixed by "-" matches rows that do not match the rest of the term.
+//
+// A term has the form field:pattern, where the string fields are:
+//
+//	path     -- the import path of the package (the default field)
+//	name     -- the package name
+//	repo     -- the repository URL, with or without its scheme
+//	module   -- the module path
+//	imports  -- the import path of any direct dependency
+//	file     -- the repository path of any source file
+//
+// In a pattern, "*" matches any string, including one containing "/", and a
+// pattern ending in "/..." matches the path before the "/..." and any path
+// beneath it. Otherwise a pattern must match exactly.
+//
+// A term of the form type:t matches rows of the given type (unknown, stdlib,
+// library, or program). The numeric fields rank, dependents, and
+// dependentRepos are compared with the operators =, <, <=, >, and >=, as in
+// "rank>0.5" or "dependents>=100"; a colon means "=".
+//
+// For example, this query matches programs in the github.com/foo organization
+// that import golang.org/x/net, excluding internal packages:
+//
+//	type:program repo:github.com/foo/* imports:golang.org/x/net/... -*/internal/*
+func ParseFilter(query string) (*Filter, error) {
+	f := new(Filter)
+	for _, word := range strings.Fields(query) {
+		neg := len(word) > 1 && word[0] == '-'
+		if neg {
+			word = word[1:]
+		}
+		match, prefix, err := parseTerm(word)
+		if err != nil {
+			return nil, fmt.Errorf("invalid term %q: %v", word, err)
+		}
+		if neg {
+			m := match
+			match = func(row *Row) bool { return !m(row) }
+		} else if len(prefix) > len(f.prefix) {
+			f.prefix = prefix
+		}
+		f.terms = append(f.terms, match)
+	}
+	return f, nil
+}
+
+// Match reports whether row satisfies f. A nil *Filter matches every row.
+func (f *Filter) Match(row *Row) bool {
+	if f == nil {
+		return true
+	}
+	for _, match := range f.terms {
+		if !match(row) {
+			return false
+		}
+	}
+	return true
+}
+
+// Prefix returns a string that is a prefix of the import path of every row
+// matched by f, for use in planning a scan. It returns "" if there is no such
+// prefix, or if f == nil.
+func (f *Filter) Prefix() string {
+	if f == nil {
+		return ""
+	}
+	return f.prefix
+}
+
+// parseTerm parses a single term of a filter query, and returns its match
+// function and the fixed prefix of import paths it can match (if any).
+func parseTerm(word string) (func(*Row) bool, string, error) {
+	i := strings.IndexAny(word, ":=<>")
+	if i < 0 {
+		word, i = "path:"+word, len("path")
+	} else if i == 0 {
+		return nil, "", fmt.Errorf("missing field name")
+	}
+	field, op, value := word[:i], word[i:i+1], word[i+1:]
+	if strings.HasPrefix(value, "=") && (op == "<" || op == ">") {
+		op, value = op+"=", value[1:]
+	}
+	if value == "" {
+		return nil, "", fmt.Errorf("missing value")
+	}
+
+	switch field {
+	case "rank":
+		return compareTerm(op, value, func(row *Row) float64 { return row.Ranking })
+	case "dependents":
+		return compareTerm(op, value, func(row *Row) float64 { return float64(row.Dependents) })
+	case "dependentRepos":
+		return compareTerm(op, value, func(row *Row) float64 { return float64(row.DependentRepos) })
+	}
+	if op != ":" && op != "=" {
+		return nil, "", fmt.Errorf("operator %q does not apply to %s", op, field)
+	}
+
+	switch field {
+	case "type":
+		t, ok := Row_Type_value[strings.ToUpper(value)]
+		if !ok {
+			return nil, "", fmt.Errorf("unknown package type %q", value)
+		}
+		return func(row *Row) bool { return row.Type == Row_Type(t) }, "", nil
+	}
+
+	match, prefix := compilePattern(value)
+	switch field {
+	case "path":
+		return func(row *Row) bool { return match(row.ImportPath) }, prefix, nil
+	case "name":
+		return func(row *Row) bool { return match(row.Name) }, "", nil
+	case "module":
+		return func(row *Row) bool { return match(row.Module) }, "", nil
+	case "repo":
+		return func(row *Row) bool {
+			return match(row.Repository) || match(trimScheme(row.Repository))
+		}, "", nil
+	case "imports":
+		return func(row *Row) bool {
+			for _, dep := range row.Directs {
+				if match(dep) {
+					return true
+				}
+			}
+			return false
+		}, "", nil
+	case "file":
+		return func(row *Row) bool {
+			for _, src := range row.SourceFiles {
+				if match(src.RepoPath) {
+					return true
+				}
+			}
+			return false
+		}, "", nil
+	}
+	return nil, "", fmt.Errorf("unknown field %q", field)
+}
+
+// compareTerm returns a match function that compares the value of a numeric
+// field to the given value.
+func compareTerm(op, value string, get func(*Row) float64) (func(*Row) bool, string, error) {
+	want, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid number %q", value)
+	}
+	var cmp func(float64) bool
+	switch op {
+	case ":", "=":
+		cmp = func(v float64) bool { return v == want }
+	case "<":
+		cmp = func(v float64) bool { return v < want }
+	case "<=":
+		cmp = func(v float64) bool { return v <= want }
+	case ">":
+		cmp = func(v float64) bool { return v > want }
+	case ">=":
+		cmp = func(v float64) bool { return v >= want }
+	}
+	return func(row *Row) bool { return cmp(get(row)) }, "", nil
+}
+
+// compilePattern returns a function that matches strings selected by the
+// pattern, and the fixed prefix of those strings.
+func compilePattern(pattern string) (func(string) bool, string) {
+	base := strings.TrimSuffix(pattern, "/...")
+	star := strings.Index(base, "*")
+	if star < 0 {
+		if base == pattern {
+			return func(s string) bool { return s == pattern }, pattern
+		}
+		return func(s string) bool {
+			return s == base || strings.HasPrefix(s, base+"/")
+		}, base
+	}
+
+	parts := strings.Split(base, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if base != pattern {
+		expr += "(/.*)?"
+	}
+	re := regexp.MustCompile(expr + "$")
+	return re.MatchString, base[:star]
+}
+
+// trimScheme removes the scheme and any ".git" suffix from a repository URL.
+func trimScheme(url string) string {
+	if i := strings.Index(url, "://"); i >= 0 {
+		url = url[i+3:]
+	}
+	return strings.TrimSuffix(url, ".git")
+}
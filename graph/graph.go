@@ -21,6 +21,9 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/creachadair/repodeps/deps"
 	"github.com/creachadair/repodeps/storage"
@@ -33,20 +36,83 @@ import (
 // TODO: Reverse index.
 
 // A Graph is an interface to a package dependency graph.
+//
+// Each row of the graph is stored under the import path of its package.
+// Other records are stored under keys beginning with auxPrefix, which sorts
+// after any valid import path. The rows and records of a tagged view of the
+// graph (see Tag) are stored the same way, beneath a prefix for the tag.
 type Graph struct {
-	st storage.Interface
+	st   storage.Interface
+	opts Options
+	pfx  string      // key prefix for a tagged view; "" for the untagged view
+	mu   *sync.Mutex // serializes index updates; shared by all views
 }
 
+// Options control optional features of a Graph. A nil *Options provides
+// default values.
+type Options struct {
+	// If true, record the history of each row alongside its current state.
+	KeepHistory bool
+
+	// The maximum number of versions to retain for each row; 0 means no limit.
+	MaxVersions int
+
+	// The maximum age of versions to retain; 0 means no limit. The most recent
+	// version of each row is retained regardless of its age.
+	MaxAge time.Duration
+
+	// If true, maintain an index of the words in the name and import path of
+	// each row, for use by Search.
+	SearchIndex bool
+}
+
+const (
+	auxPrefix    = "\xff"
+	repoKind     = "repo"     // repository records
+	histKind     = "hist"     // row versions
+	tagKind      = "tag"      // tagged views
+	indexKind    = "index"    // marks complete indexes
+	byRepoKind   = "byrepo"   // repository index entries
+	byDigestKind = "bydigest" // digest index entries
+	searchKind   = "search"   // search index entries
+)
+
+// auxKey returns a storage key for the auxiliary record of the given kind
+// identified by the specified name.
+func auxKey(kind, name string) string { return auxPrefix + kind + "\x00" + name }
+
+// IsRowKey reports whether key is the storage key of a row in the untagged
+// view of a graph, rather than a tagged row or an auxiliary record.
+func IsRowKey(key string) bool { return !strings.HasPrefix(key, auxPrefix) }
+
+// key returns the storage key for k in the view of g.
+func (g *Graph) key(k string) string { return g.pfx + k }
+
 // New constructs a graph handle for the given storage.
-func New(st storage.Interface) *Graph { return &Graph{st: st} }
+func New(st storage.Interface, opts *Options) *Graph {
+	g := &Graph{st: st, mu: new(sync.Mutex)}
+	if opts != nil {
+		g.opts = *opts
+	}
+	return g
+}
 
-// Add adds the specified package to the graph. If an entry already exists for
-// the specified package, it is replaced.
-func (g *Graph) Add(ctx context.Context, repo *deps.Repo, pkg *deps.Package) error {
-	var url string
-	if len(repo.Remotes) != 0 {
-		url = repo.Remotes[0].Url
+// Tag returns a view of the graph whose rows and records are stored
+// separately from those of any other tag. The empty tag denotes the default
+// untagged view, which is the one returned by New.
+func (g *Graph) Tag(tag string) *Graph {
+	view := &Graph{st: g.st, opts: g.opts, mu: g.mu}
+	if tag != "" {
+		view.pfx = auxKey(tagKind, tag+"\x00")
 	}
+	return view
+}
+
+// Add adds the specified package to the graph, attributed to the repository
+// URL reported by repo.URL. If an entry already exists for the specified
+// package, it is replaced.
+func (g *Graph) Add(ctx context.Context, repo *deps.Repo, pkg *deps.Package) error {
+	url := repo.URL()
 	var files []*Row_File
 	for _, file := range pkg.Sources {
 		files = append(files, &Row_File{
@@ -57,41 +123,86 @@ func (g *Graph) Add(ctx context.Context, repo *deps.Repo, pkg *deps.Package) err
 	sort.Slice(files, func(i, j int) bool {
 		return files[i].RepoPath < files[j].RepoPath
 	})
-	return g.st.Store(ctx, pkg.ImportPath, &Row{
+	row := &Row{
 		Name:        pkg.Name,
 		ImportPath:  pkg.ImportPath,
 		Repository:  url,
 		Directs:     pkg.Imports,
 		SourceFiles: files,
 		Type:        Row_Type(pkg.Type),
-	})
+		Module:      pkg.Module,
+	}
+	if err := g.storeRow(ctx, row); err != nil {
+		return err
+	} else if g.opts.KeepHistory {
+		return g.recordVersion(ctx, row.ImportPath, &Version{Row: row, Digest: repo.Digest})
+	}
+	return nil
 }
 
-// AddAll calls Add for each package defined in the specified repo.
+// AddAll calls Add for each package defined in the specified repo, and
+// records the repository-level information for repo.
 func (g *Graph) AddAll(ctx context.Context, repo *deps.Repo) error {
 	for _, pkg := range repo.Packages {
 		if err := g.Add(ctx, repo, pkg); err != nil {
 			return fmt.Errorf("package %q: %v", pkg.ImportPath, err)
 		}
 	}
+	url := repo.URL()
+	if url == "" {
+		return nil // nothing to attribute the repository record to
+	}
+	rec := &Repo{Repository: url}
+	for _, mod := range repo.Vendored {
+		rec.Vendored = append(rec.Vendored, &Repo_Module{
+			Path:     mod.Path,
+			Version:  mod.Version,
+			Replace:  mod.Replace,
+			Packages: mod.Packages,
+		})
+	}
+	for _, sub := range repo.Submodules {
+		rec.Submodules = append(rec.Submodules, &Repo_Submodule{
+			Path:       sub.Path,
+			Repository: sub.Url,
+		})
+	}
+	if err := g.st.Store(ctx, g.key(auxKey(repoKind, rec.Repository)), rec); err != nil {
+		return fmt.Errorf("repository %q: %v", rec.Repository, err)
+	}
 	return nil
 }
 
 // Row loads the complete row for the specified import path.
 func (g *Graph) Row(ctx context.Context, pkg string) (*Row, error) {
 	var row Row
-	if err := g.st.Load(ctx, pkg, &row); err != nil {
+	if err := g.st.Load(ctx, g.key(pkg), &row); err != nil {
 		return nil, err
 	}
 	return &row, nil
 }
 
+// PutRow stores row under its import path, replacing any existing row. Unlike
+// Add, PutRow does not record a version of the row in the history.
+func (g *Graph) PutRow(ctx context.Context, row *Row) error {
+	return g.storeRow(ctx, row)
+}
+
 // List calls f with each key in the graph lexicographically greater than or
 // equal to start.  If f reports an error, scanning terminates. If the error is
 // storage.ErrStopScan, List returns nil. Otherwise, List returns the error
 // from f.
 func (g *Graph) List(ctx context.Context, start string, f func(string) error) error {
-	return g.st.Scan(ctx, start, f)
+	return g.st.Scan(ctx, g.key(start), func(key string) error {
+		if !strings.HasPrefix(key, g.pfx) {
+			return storage.ErrStopScan // no more keys in this view
+		}
+		key = strings.TrimPrefix(key, g.pfx)
+		if strings.HasPrefix(key, auxPrefix) {
+			return storage.ErrStopScan // no more rows
+		}
+		return f(key)
+	})
 }
 
 // Scan calls f with each row in the graph whose key is lexicographically
@@ -113,17 +224,61 @@ func (g *Graph) Scan(ctx context.Context, start string, f func(*Row) error) erro
 // action is taken for the row.
 func (g *Graph) ScanUpdate(ctx context.Context, prefix string, f func(*Row) bool) error {
 	return g.Scan(ctx, prefix, func(row *Row) error {
-		key := row.ImportPath
-		if f(row) {
-			return g.st.Store(ctx, key, row)
+		if !f(row) {
+			return nil
 		}
-		return nil
+		return g.storeRow(ctx, row)
 	})
 }
 
-// Remove removes the row for pkg from g.
+// Remove removes the row for pkg from g. If g keeps history, the removal is
+// recorded as a new version of the row.
 func (g *Graph) Remove(ctx context.Context, pkg string) error {
-	return g.st.Delete(ctx, pkg)
+	if err := g.deleteRow(ctx, pkg); err != nil {
+		return err
+	} else if g.opts.KeepHistory {
+		return g.recordVersion(ctx, pkg, &Version{Removed: true})
+	}
+	return nil
+}
+
+// Repo loads the repository record for the specified repository URL.
+func (g *Graph) Repo(ctx context.Context, url string) (*Repo, error) {
+	var rec Repo
+	if err := g.st.Load(ctx, g.key(auxKey(repoKind, url)), &rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// ScanRepos calls f with each repository record in the graph whose URL is
+// lexicographically greater than or equal to start. If f reports an error,
+// scanning terminates. If the error is storage.ErrStopScan, ScanRepos returns
+// nil. Otherwise ScanRepos returns the error from f.
+func (g *Graph) ScanRepos(ctx context.Context, start string, f func(*Repo) error) error {
+	pfx := g.key(auxKey(repoKind, ""))
+	return g.st.Scan(ctx, pfx+start, func(key string) error {
+		if !strings.HasPrefix(key, pfx) {
+			return storage.ErrStopScan // no more repositories
+		}
+		var rec Repo
+		if err := g.st.Load(ctx, key, &rec); err != nil {
+			return err
+		}
+		return f(&rec)
+	})
+}
+
+// PutRepo stores rec as the repository record for its URL, replacing any
+// existing record.
+func (g *Graph) PutRepo(ctx context.Context, rec *Repo) error {
+	return g.st.Store(ctx, g.key(auxKey(repoKind, rec.Repository)), rec)
+}
+
+// RemoveRepo removes the repository record for url from g. It does not
+// remove the rows for packages defined in that repository.
+func (g *Graph) RemoveRepo(ctx context.Context, url string) error {
+	return g.st.Delete(ctx, g.key(auxKey(repoKind, url)))
 }
 
 // MatchImporters calls f(q, p) for each package p that directly depends on any
@@ -145,3 +300,9 @@ func (r *Row) MarshalJSON() ([]byte, error) { return protojson.Marshal(r) }
 
 // UnmarshalJSON implements json.Unmarshaler for a Row by delegating to jsonpb.
 func (r *Row) UnmarshalJSON(data []byte) error { return protojson.Unmarshal(data, r) }
+
+// MarshalJSON implements json.Marshaler for a Repo by delegating to protojson.
+func (r *Repo) MarshalJSON() ([]byte, error) { return protojson.Marshal(r) }
+
+// UnmarshalJSON implements json.Unmarshaler for a Repo by delegating to protojson.
+func (r *Repo) UnmarshalJSON(data []byte) error { return protojson.Unmarshal(data, r) }
@@ -0,0 +1,944 @@
+package graph_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	cgraph "github.com/cayleygraph/cayley/graph"
+	cmem "github.com/cayleygraph/cayley/graph/memstore"
+	"github.com/cayleygraph/quad"
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/blob/memstore"
+	"github.com/creachadair/repodeps/deps"
+	"github.com/creachadair/repodeps/graph"
+	"github.com/creachadair/repodeps/storage"
+	"google.golang.org/protobuf/proto"
+)
+
+func newRepo(digest string, pkgs ...*deps.Package) *deps.Repo {
+	return &deps.Repo{
+		Remotes:  []*deps.Remote{{Name: "origin", Url: "https://github.com/x/y"}},
+		Digest:   digest,
+		Packages: pkgs,
+	}
+}
+
+func pkg(path string, imports ...string) *deps.Package {
+	return &deps.Package{Name: "p", ImportPath: path, Imports: imports}
+}
+
+// scanKeys returns the import paths of the rows visited by scan.
+func scanKeys(t *testing.T, scan func(func(*graph.Row) error) error) []string {
+	t.Helper()
+	var keys []string
+	if err := scan(func(row *graph.Row) error {
+		keys = append(keys, row.ImportPath)
+		return nil
+	}); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	return keys
+}
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	g := graph.New(storage.NewBlob(memstore.New()), &graph.Options{
+		KeepHistory: true,
+		MaxVersions: 2,
+	})
+
+	// Version 1: a → b
+	if err := g.AddAll(ctx, newRepo("d1", pkg("x/a", "x/b"), pkg("x/b"))); err != nil {
+		t.Fatalf("AddAll 1: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	mid := time.Now()
+	time.Sleep(time.Millisecond)
+
+	// Version 2: a → c, b removed.
+	if err := g.AddAll(ctx, newRepo("d2", pkg("x/a", "x/c"), pkg("x/c"))); err != nil {
+		t.Fatalf("AddAll 2: %v", err)
+	}
+	if err := g.Remove(ctx, "x/b"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	// The current view should not include history records.
+	cur := scanKeys(t, func(f func(*graph.Row) error) error { return g.Scan(ctx, "", f) })
+	if got, want := cur, []string{"x/a", "x/c"}; !equal(got, want) {
+		t.Errorf("Scan: got %q, want %q", got, want)
+	}
+
+	checkAt := func(snap graph.Snapshot, want ...string) {
+		t.Helper()
+		got := scanKeys(t, func(f func(*graph.Row) error) error { return g.ScanAt(ctx, snap, "", f) })
+		if !equal(got, want) {
+			t.Errorf("ScanAt(%+v): got %q, want %q", snap, got, want)
+		}
+	}
+	checkAt(graph.Snapshot{Time: mid}, "x/a", "x/b")
+	checkAt(graph.Snapshot{Time: time.Now()}, "x/a", "x/c")
+	checkAt(graph.Snapshot{Digest: "d1"}, "x/a", "x/b")
+	checkAt(graph.Snapshot{Digest: "d2"}, "x/a", "x/c")
+	checkAt(graph.Snapshot{Time: mid, Digest: "d2"})
+
+	// As of mid, x/a depended on x/b.
+	if err := g.ScanAt(ctx, graph.Snapshot{Time: mid}, "x/a", func(row *graph.Row) error {
+		if got := row.Directs; len(got) != 1 || got[0] != "x/b" {
+			t.Errorf("ScanAt: %s directs: got %q, want [x/b]", row.ImportPath, got)
+		}
+		return storage.ErrStopScan
+	}); err != nil {
+		t.Errorf("ScanAt failed: %v", err)
+	}
+
+	// Adding an identical row should not create a new version, and the
+	// retention limit should cap the number of versions.
+	if err := g.AddAll(ctx, newRepo("d2", pkg("x/a", "x/c"))); err != nil {
+		t.Fatalf("AddAll 3: %v", err)
+	}
+	if err := g.AddAll(ctx, newRepo("d3", pkg("x/a"))); err != nil {
+		t.Fatalf("AddAll 4: %v", err)
+	}
+	var digests []string
+	if err := g.Versions(ctx, "x/a", func(v *graph.Version) error {
+		digests = append(digests, v.Digest)
+		return nil
+	}); err != nil {
+		t.Fatalf("Versions failed: %v", err)
+	}
+	if want := []string{"d2", "d3"}; !equal(digests, want) {
+		t.Errorf("Versions: got %q, want %q", digests, want)
+	}
+}
+
+func TestTag(t *testing.T) {
+	ctx := context.Background()
+	g := graph.New(storage.NewBlob(memstore.New()), nil)
+	v1 := g.Tag("v1")
+
+	if err := g.AddAll(ctx, newRepo("d1", pkg("x/a", "x/b"), pkg("x/b"))); err != nil {
+		t.Fatalf("AddAll: %v", err)
+	}
+	if err := v1.AddAll(ctx, newRepo("d2", pkg("x/a", "x/c"), pkg("x/c"))); err != nil {
+		t.Fatalf("AddAll v1: %v", err)
+	}
+
+	check := func(g *graph.Graph, tag string, want ...string) {
+		t.Helper()
+		got := scanKeys(t, func(f func(*graph.Row) error) error { return g.Scan(ctx, "", f) })
+		if !equal(got, want) {
+			t.Errorf("Scan %q: got %q, want %q", tag, got, want)
+		}
+	}
+	check(g, "", "x/a", "x/b")
+	check(v1, "v1", "x/a", "x/c")
+	check(g.Tag("v2"), "v2")
+
+	// Removing from one view does not affect the other.
+	if err := v1.Remove(ctx, "x/a"); err != nil {
+		t.Fatalf("Remove v1: %v", err)
+	}
+	check(g, "", "x/a", "x/b")
+	check(v1, "v1", "x/c")
+	if row, err := g.Row(ctx, "x/a"); err != nil {
+		t.Errorf("Row x/a: %v", err)
+	} else if got := row.Directs; len(got) != 1 || got[0] != "x/b" {
+		t.Errorf("Row x/a directs: got %q, want [x/b]", got)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	ctx := context.Background()
+	g := graph.New(storage.NewBlob(memstore.New()), nil)
+	add := func(url string, pkgs ...*deps.Package) {
+		t.Helper()
+		repo := &deps.Repo{Remotes: []*deps.Remote{{Name: "origin", Url: url}}, Packages: pkgs}
+		if err := g.AddAll(ctx, repo); err != nil {
+			t.Fatalf("AddAll %q: %v", url, err)
+		}
+	}
+	mpkg := func(mod, path string, imports ...string) *deps.Package {
+		p := pkg(path, imports...)
+		p.Module = mod
+		return p
+	}
+	add("r1", mpkg("m1", "m1/a", "m1/b", "m2/c", "fmt"), mpkg("m1", "m1/b", "m2/c", "m2/d"))
+	add("r2", mpkg("m2", "m2/c", "m1/a"), mpkg("m2", "m2/d"), pkg("x/e", "m1/a"))
+
+	check := func(level graph.Level, want string) {
+		t.Helper()
+		nodes, err := g.Aggregate(ctx, level)
+		if err != nil {
+			t.Fatalf("Aggregate %v: %v", level, err)
+		}
+		var got string
+		for _, n := range nodes {
+			got += fmt.Sprintf("%s[%d]", n.Name, n.Packages)
+			for _, e := range n.Edges {
+				got += fmt.Sprintf(" %s=%d", e.Target, e.Weight)
+			}
+			got += "; "
+		}
+		if got != want {
+			t.Errorf("Aggregate %v: got %q, want %q", level, got, want)
+		}
+	}
+	check(graph.ByRepository, "r1[2] r2=3; r2[3] r1=2; ")
+	check(graph.ByModule, "m1[2] m2=3; m2[2] m1=1; ")
+}
+
+func TestCycles(t *testing.T) {
+	ctx := context.Background()
+	g := graph.New(storage.NewBlob(memstore.New()), nil)
+	for _, repo := range []struct {
+		url  string
+		pkgs []*deps.Package
+	}{
+		{"r1", []*deps.Package{pkg("r1/a", "r2/b"), pkg("r1/x")}},
+		{"r2", []*deps.Package{pkg("r2/b", "r3/c"), pkg("r2/y", "r4/d")}},
+		{"r3", []*deps.Package{pkg("r3/c", "r1/x")}},
+		{"r4", []*deps.Package{pkg("r4/d", "r1/x")}},
+		{"r5", []*deps.Package{pkg("r5/e", "r1/a")}},
+	} {
+		rec := &deps.Repo{Remotes: []*deps.Remote{{Name: "origin", Url: repo.url}}, Packages: repo.pkgs}
+		if err := g.AddAll(ctx, rec); err != nil {
+			t.Fatalf("AddAll %q: %v", repo.url, err)
+		}
+	}
+
+	cycles, err := g.Cycles(ctx, graph.ByRepository)
+	if err != nil {
+		t.Fatalf("Cycles failed: %v", err)
+	}
+	if len(cycles) != 1 {
+		t.Fatalf("Cycles: got %d cycles, want 1", len(cycles))
+	}
+	if got, want := cycles[0].Nodes, []string{"r1", "r2", "r3", "r4"}; !equal(got, want) {
+		t.Errorf("Cycle nodes: got %q, want %q", got, want)
+	}
+	var edges []string
+	for _, e := range cycles[0].Edges {
+		edges = append(edges, e.Source+">"+e.Target)
+	}
+	if want := []string{"r1/a>r2/b", "r2/b>r3/c", "r2/y>r4/d", "r3/c>r1/x", "r4/d>r1/x"}; !equal(edges, want) {
+		t.Errorf("Cycle edges: got %q, want %q", edges, want)
+	}
+}
+
+func TestPaths(t *testing.T) {
+	ctx := context.Background()
+	g := graph.New(storage.NewBlob(memstore.New()), nil)
+	if err := g.AddAll(ctx, newRepo("",
+		pkg("x/a", "x/b", "x/c", "fmt"),
+		pkg("x/b", "x/d"),
+		pkg("x/c", "x/d", "x/e"),
+		pkg("x/e", "x/d"),
+		pkg("x/f", "x/b"),
+		pkg("fmt", "x/d"),
+		pkg("x/d"),
+	)); err != nil {
+		t.Fatalf("AddAll: %v", err)
+	}
+	check := func(srcs []string, opts *graph.PathOptions, want ...string) {
+		t.Helper()
+		paths, err := g.Paths(ctx, srcs, "x/d", opts)
+		if err != nil {
+			t.Fatalf("Paths %q: %v", srcs, err)
+		}
+		var got []string
+		for _, p := range paths {
+			got = append(got, strings.Join(p, " "))
+		}
+		if !equal(got, want) {
+			t.Errorf("Paths %q: got %q, want %q", srcs, got, want)
+		}
+	}
+	check([]string{"x/a"}, nil, "x/a fmt x/d")
+	check([]string{"x/a"}, &graph.PathOptions{Limit: 10},
+		"x/a fmt x/d", "x/a x/b x/d", "x/a x/c x/d", "x/a x/c x/e x/d")
+	check([]string{"x/a"}, &graph.PathOptions{
+		Limit: 2,
+		Skip:  func(pkg string, _ *graph.Row) bool { return pkg == "fmt" || pkg == "x/b" },
+	}, "x/a x/c x/d", "x/a x/c x/e x/d")
+	check([]string{"x/a", "x/f"}, &graph.PathOptions{Limit: 2}, "x/a fmt x/d", "x/a x/b x/d")
+	check([]string{"x/e", "x/f"}, nil, "x/e x/d")
+	check([]string{"x/d"}, nil, "x/d")
+	check([]string{"nonesuch"}, nil)
+}
+
+func TestClosure(t *testing.T) {
+	ctx := context.Background()
+	g := graph.New(storage.NewBlob(memstore.New()), nil)
+	if err := g.AddAll(ctx, newRepo("",
+		pkg("x/a", "x/b", "fmt"),
+		pkg("x/b", "x/c", "y/z"),
+		pkg("x/c", "fmt"),
+		pkg("x/d", "x/c"),
+	)); err != nil {
+		t.Fatalf("AddAll: %v", err)
+	}
+	check := func(roots []string, opts *graph.ClosureOptions, want string) {
+		t.Helper()
+		depth, err := g.Closure(ctx, roots, opts)
+		if err != nil {
+			t.Fatalf("Closure %q: %v", roots, err)
+		}
+		var keys []string
+		for pkg := range depth {
+			keys = append(keys, pkg)
+		}
+		sort.Strings(keys)
+		var got []string
+		for _, pkg := range keys {
+			got = append(got, fmt.Sprintf("%s=%d", pkg, depth[pkg]))
+		}
+		if s := strings.Join(got, " "); s != want {
+			t.Errorf("Closure %q: got %q, want %q", roots, s, want)
+		}
+	}
+	check([]string{"x/a"}, nil, "fmt=1 x/a=0 x/b=1 x/c=2 y/z=2")
+	check([]string{"x/a"}, &graph.ClosureOptions{MaxDepth: 1}, "fmt=1 x/a=0 x/b=1")
+	check([]string{"x/a"}, &graph.ClosureOptions{
+		Skip: func(pkg string, _ *graph.Row) bool { return pkg == "x/b" },
+	}, "fmt=1 x/a=0")
+	check([]string{"x/c"}, &graph.ClosureOptions{Reverse: true}, "x/a=2 x/b=1 x/c=0 x/d=1")
+	check([]string{"fmt"}, &graph.ClosureOptions{Reverse: true, MaxDepth: 1}, "fmt=0 x/a=1 x/c=1")
+}
+
+func TestLayer(t *testing.T) {
+	str := func(v [][]string) string {
+		var parts []string
+		for _, elt := range v {
+			parts = append(parts, strings.Join(elt, ","))
+		}
+		return strings.Join(parts, " | ")
+	}
+	tests := []struct {
+		adj                    map[string][]string
+		layers, cycles, blocks string
+	}{
+		{map[string][]string{}, "", "", ""},
+		{map[string][]string{
+			"a": {"b", "c", "x"}, "b": {"c"}, "c": nil, "d": {"c", "c"},
+		}, "c | b,d | a", "", ""},
+		{map[string][]string{
+			"a": {"b"}, "b": {"c"}, "c": {"b"}, "d": nil, "e": {"e"}, "f": {"d"},
+		}, "d | f", "b,c | e", "a"},
+	}
+	for _, test := range tests {
+		got := graph.Layer(test.adj)
+		if s := str(got.Layers); s != test.layers {
+			t.Errorf("Layer %v: layers are %q, want %q", test.adj, s, test.layers)
+		}
+		if s := str(got.Cycles); s != test.cycles {
+			t.Errorf("Layer %v: cycles are %q, want %q", test.adj, s, test.cycles)
+		}
+		if s := strings.Join(got.Blocked, ","); s != test.blocks {
+			t.Errorf("Layer %v: blocked are %q, want %q", test.adj, s, test.blocks)
+		}
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	mem := memstore.New()
+	st := storage.NewBlob(mem)
+	g := graph.New(st, nil)
+	if err := g.AddAll(ctx, newRepo("d1", pkg("x/a", "x/b"), pkg("x/b"))); err != nil {
+		t.Fatalf("AddAll: %v", err)
+	}
+	if err := g.Tag("v1").AddAll(ctx, newRepo("d2", pkg("x/c"))); err != nil {
+		t.Fatalf("AddAll v1: %v", err)
+	}
+
+	// Plant a misplaced row and an undecodable row.
+	if err := st.Store(ctx, "x/old", &graph.Row{ImportPath: "x/new"}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err := mem.Put(ctx, blob.PutOptions{Key: "x/bad", Data: []byte("\xff\xff\xff")}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	check := func(repair bool, want ...string) {
+		t.Helper()
+		var got []string
+		if err := g.Check(ctx, func(string) bool { return repair }, func(p *graph.Problem) error {
+			got = append(got, p.Kind+" "+p.Key)
+			if p.Repaired != repair {
+				t.Errorf("Problem %+v: repaired is %v, want %v", p, p.Repaired, repair)
+			}
+			return nil
+		}); err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if !equal(got, want) {
+			t.Errorf("Check: got %q, want %q", got, want)
+		}
+	}
+	check(false, "badRow x/bad", "keyMismatch x/old")
+	check(true, "badRow x/bad", "keyMismatch x/old")
+	check(false)
+
+	got := scanKeys(t, func(f func(*graph.Row) error) error { return g.Scan(ctx, "", f) })
+	if want := []string{"x/a", "x/b", "x/new"}; !equal(got, want) {
+		t.Errorf("Scan after repair: got %q, want %q", got, want)
+	}
+
+	var tags []string
+	if err := g.Tags(ctx, func(tag string) error {
+		tags = append(tags, tag)
+		return nil
+	}); err != nil {
+		t.Fatalf("Tags failed: %v", err)
+	} else if want := []string{"v1"}; !equal(tags, want) {
+		t.Errorf("Tags: got %q, want %q", tags, want)
+	}
+}
+
+func TestQuadsRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	g := graph.New(storage.NewBlob(memstore.New()), nil)
+	same := []byte("same contents")
+	a := pkg("x/a", "x/b", "y/missing")
+	a.Type = deps.Package_PROGRAM
+	a.Module = "x"
+	a.Sources = []*deps.File{{RepoPath: "a/doc.go", Digest: same}, {RepoPath: "a/main.go", Digest: []byte("main")}}
+	b := pkg("x/b")
+	b.Sources = []*deps.File{{RepoPath: "b/doc.go", Digest: same}}
+
+	// Files with the same contents, or without digests, remain distinct.
+	c := pkg("x/c")
+	c.Sources = []*deps.File{
+		{RepoPath: "c/one.go", Digest: same}, {RepoPath: "c/two.go", Digest: same},
+		{RepoPath: "c/three.go"}, {RepoPath: "c/four.go"},
+	}
+	if err := g.AddAll(ctx, newRepo("d1", a, b, c)); err != nil {
+		t.Fatalf("AddAll: %v", err)
+	}
+
+	var want []*graph.Row
+	if err := g.Scan(ctx, "", func(row *graph.Row) error {
+		want = append(want, row)
+		return nil
+	}); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+
+	for _, opts := range []*graph.QuadOptions{nil, {StableIRIs: true}} {
+		var buf bytes.Buffer
+		if err := g.WriteQuads(ctx, &buf, opts); err != nil {
+			t.Fatalf("WriteQuads(%+v): %v", opts, err)
+		}
+		var got []*graph.Row
+		if err := graph.ReadQuads(&buf, func(row *graph.Row) error {
+			got = append(got, row)
+			return nil
+		}); err != nil {
+			t.Fatalf("ReadQuads(%+v): %v", opts, err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("ReadQuads(%+v): got %d rows, want %d", opts, len(got), len(want))
+		}
+		for i := range want {
+			if !proto.Equal(got[i], want[i]) {
+				t.Errorf("Row %d (%+v): got %v, want %v", i, opts, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestQuadsAmbiguousFiles(t *testing.T) {
+	// In the older encoding, files were identified only by digest. A package
+	// referring to one such node with several paths more than once cannot be
+	// decoded.
+	const input = `<dep:pkg/x> <rdf:type> <dep:Package> .
+<dep:pkg/x> <dep:import-path> "x" .
+<dep:pkg/x> <dep:defined-in> <https://x> .
+<dep:pkg/x> <dep:has-file> <dep:file/abcd> .
+<dep:pkg/y> <rdf:type> <dep:Package> .
+<dep:pkg/y> <dep:import-path> "y" .
+<dep:pkg/y> <dep:defined-in> <https://x> .
+<dep:pkg/y> <dep:has-file> <dep:file/abcd> .
+<dep:pkg/y> <dep:has-file> <dep:file/abcd> .
+<dep:file/abcd> <dep:digest> "abcd" .
+<dep:file/abcd> <dep:repo-path> "x/a.go" .
+<dep:file/abcd> <dep:repo-path> "y/a.go" .
+`
+	err := graph.ReadQuads(strings.NewReader(input), func(*graph.Row) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "indistinguishable") {
+		t.Errorf("ReadQuads: got error %v, want indistinguishable files", err)
+	}
+}
+
+func TestIncrementalQuads(t *testing.T) {
+	ctx := context.Background()
+	g := graph.New(storage.NewBlob(memstore.New()), &graph.Options{KeepHistory: true})
+
+	// Version 1: a → b, c → b.
+	if err := g.AddAll(ctx, newRepo("d1", pkg("x/a", "x/b"), pkg("x/b"), pkg("x/c", "x/b"))); err != nil {
+		t.Fatalf("AddAll 1: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	mid := time.Now()
+	time.Sleep(time.Millisecond)
+
+	// Version 2: a → c, b removed.
+	if err := g.AddAll(ctx, newRepo("d2", pkg("x/a", "x/c"))); err != nil {
+		t.Fatalf("AddAll 2: %v", err)
+	}
+	if err := g.Remove(ctx, "x/b"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	// Stable IRIs do not depend on the order of encoding.
+	export := func(opts *graph.QuadOptions) string {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := g.WriteQuads(ctx, &buf, opts); err != nil {
+			t.Fatalf("WriteQuads(%+v): %v", opts, err)
+		}
+		return buf.String()
+	}
+	stable := &graph.QuadOptions{StableIRIs: true}
+	if a, b := export(stable), export(stable); a != b {
+		t.Errorf("Stable exports differ:\n%s\n--\n%s", a, b)
+	} else if iri := "<" + string(graph.PackageIRI("x/a")) + ">"; !strings.Contains(a, iri) {
+		t.Errorf("Stable export does not mention %s:\n%s", iri, a)
+	}
+
+	// Only x/a changed and x/b was removed since mid. Since x/c has a row, it
+	// is not reported as missing.
+	dec := graph.NewQuadDecoder()
+	if err := g.EncodeToQuads(ctx, &graph.QuadOptions{Since: mid}, func(q quad.Quad) error {
+		dec.Add(q)
+		return nil
+	}); err != nil {
+		t.Fatalf("EncodeToQuads: %v", err)
+	}
+	var rows, removed []string
+	if err := dec.Rows(func(row *graph.Row) error {
+		rows = append(rows, row.ImportPath)
+		return nil
+	}); err != nil {
+		t.Fatalf("Rows: %v", err)
+	}
+	if err := dec.Removed(func(pkg string) error {
+		removed = append(removed, pkg)
+		return nil
+	}); err != nil {
+		t.Fatalf("Removed: %v", err)
+	}
+	if want := []string{"x/a"}; !equal(rows, want) {
+		t.Errorf("Changed rows: got %q, want %q", rows, want)
+	}
+	if want := []string{"x/b"}; !equal(removed, want) {
+		t.Errorf("Removed rows: got %q, want %q", removed, want)
+	}
+}
+
+func TestReplaceQuads(t *testing.T) {
+	ctx := context.Background()
+	g := graph.New(storage.NewBlob(memstore.New()), &graph.Options{KeepHistory: true})
+	withFile := func(p *deps.Package, path, digest string) *deps.Package {
+		p.Sources = append(p.Sources, &deps.File{RepoPath: path, Digest: []byte(digest)})
+		return p
+	}
+
+	// Version 1: a → b, c → b, d → y/missing, with d in its own repository.
+	other := &deps.Repo{
+		Remotes:  []*deps.Remote{{Name: "origin", Url: "https://github.com/z/w"}},
+		Packages: []*deps.Package{withFile(pkg("z/d", "y/missing"), "d.go", "d")},
+	}
+	for _, repo := range []*deps.Repo{
+		newRepo("d1", withFile(pkg("x/a", "x/b"), "a.go", "a1"), pkg("x/b"), pkg("x/c", "x/b")),
+		other,
+	} {
+		if err := g.AddAll(ctx, repo); err != nil {
+			t.Fatalf("AddAll: %v", err)
+		}
+	}
+
+	// encode returns the quads of an encoding of g with opts.
+	encode := func(opts *graph.QuadOptions) []quad.Quad {
+		t.Helper()
+		var quads []quad.Quad
+		if err := g.EncodeToQuads(ctx, opts, func(q quad.Quad) error {
+			quads = append(quads, q)
+			return nil
+		}); err != nil {
+			t.Fatalf("EncodeToQuads(%+v): %v", opts, err)
+		}
+		return quads
+	}
+	// dump returns the sorted quads of qs in N-quads format.
+	dump := func(qs *cmem.QuadStore) []string {
+		t.Helper()
+		var out []string
+		it := qs.QuadsAllIterator()
+		defer it.Close()
+		for it.Next(ctx) {
+			out = append(out, qs.Quad(it.Result()).NQuad())
+		}
+		sort.Strings(out)
+		return out
+	}
+	load := func(quads []quad.Quad) *cmem.QuadStore {
+		qs := cmem.New()
+		for _, q := range quads {
+			qs.AddQuad(q)
+		}
+		return qs
+	}
+	stable := &graph.QuadOptions{StableIRIs: true}
+	old := encode(stable)
+	time.Sleep(time.Millisecond)
+	mid := time.Now()
+	time.Sleep(time.Millisecond)
+
+	// Version 2: a → c with a new file, b is removed but still imported by c,
+	// d and its repository are removed, and e → y/missing is added.
+	if err := g.AddAll(ctx, newRepo("d2", withFile(pkg("x/a", "x/c"), "a.go", "a2"), pkg("x/e", "y/missing"))); err != nil {
+		t.Fatalf("AddAll 2: %v", err)
+	}
+	for _, pkg := range []string{"x/b", "z/d"} {
+		if err := g.Remove(ctx, pkg); err != nil {
+			t.Fatalf("Remove %q: %v", pkg, err)
+		}
+	}
+	want := dump(load(encode(stable)))
+
+	for _, opts := range []*graph.QuadOptions{
+		{Since: mid},
+		{Packages: []string{"x/a", "x/b", "x/e", "z/d"}},
+	} {
+		qs := load(old)
+		tx, err := graph.ReplaceQuads(ctx, qs, encode(opts))
+		if err != nil {
+			t.Fatalf("ReplaceQuads(%+v): %v", opts, err)
+		}
+		if err := qs.ApplyDeltas(tx.Deltas, cgraph.IgnoreOpts{IgnoreDup: true, IgnoreMissing: true}); err != nil {
+			t.Fatalf("ApplyDeltas(%+v): %v", opts, err)
+		}
+		if got := dump(qs); !equal(got, want) {
+			t.Errorf("Updated store (%+v):\ngot  %s\nwant %s",
+				opts, strings.Join(got, "\n     "), strings.Join(want, "\n     "))
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	rows := []*graph.Row{{
+		ImportPath: "github.com/foo/a",
+		Name:       "a",
+		Type:       graph.Row_LIBRARY,
+		Repository: "https://github.com/foo/a.git",
+		Ranking:    0.5,
+		Dependents: 10,
+		Directs:    []string{"golang.org/x/net/http2", "fmt"},
+	}, {
+		ImportPath:  "github.com/foo/a/cmd/tool",
+		Name:        "main",
+		Type:        graph.Row_PROGRAM,
+		Repository:  "https://github.com/foo/a.git",
+		Ranking:     0.1,
+		Directs:     []string{"github.com/foo/a"},
+		SourceFiles: []*graph.Row_File{{RepoPath: "cmd/tool/main.go"}},
+	}, {
+		ImportPath: "github.com/foo/a/internal/x",
+		Name:       "x",
+		Type:       graph.Row_LIBRARY,
+		Repository: "https://github.com/foo/a.git",
+		Directs:    []string{"golang.org/x/net"},
+	}, {
+		ImportPath: "fmt",
+		Name:       "fmt",
+		Type:       graph.Row_STDLIB,
+		Ranking:    2,
+		Dependents: 500,
+	}}
+	tests := []struct {
+		query, prefix string
+		want          []string
+	}{
+		{"", "", []string{"github.com/foo/a", "github.com/foo/a/cmd/tool", "github.com/foo/a/internal/x", "fmt"}},
+		{"fmt", "fmt", []string{"fmt"}},
+		{"github.com/foo/a/...", "github.com/foo/a", []string{"github.com/foo/a", "github.com/foo/a/cmd/tool", "github.com/foo/a/internal/x"}},
+		{"path:github.com/*/cmd/*", "github.com/", []string{"github.com/foo/a/cmd/tool"}},
+		{"-*/internal/* repo:github.com/foo/*", "", []string{"github.com/foo/a", "github.com/foo/a/cmd/tool"}},
+		{"repo:https://github.com/foo/a.git type:program", "", []string{"github.com/foo/a/cmd/tool"}},
+		{"imports:golang.org/x/net/...", "", []string{"github.com/foo/a", "github.com/foo/a/internal/x"}},
+		{"imports:golang.org/x/net", "", []string{"github.com/foo/a/internal/x"}},
+		{"type:stdlib", "", []string{"fmt"}},
+		{"rank>0.1", "", []string{"github.com/foo/a", "fmt"}},
+		{"rank>=0.1 rank<1", "", []string{"github.com/foo/a", "github.com/foo/a/cmd/tool"}},
+		{"dependents:10", "", []string{"github.com/foo/a"}},
+		{"-dependents<=10", "", []string{"fmt"}},
+		{"name:main file:cmd/*", "", []string{"github.com/foo/a/cmd/tool"}},
+		{"module:*", "", []string{"github.com/foo/a", "github.com/foo/a/cmd/tool", "github.com/foo/a/internal/x", "fmt"}},
+		{"github.com/... github.com/foo/a/...", "github.com/foo/a", []string{"github.com/foo/a", "github.com/foo/a/cmd/tool", "github.com/foo/a/internal/x"}},
+	}
+	for _, test := range tests {
+		f, err := graph.ParseFilter(test.query)
+		if err != nil {
+			t.Errorf("ParseFilter(%q): unexpected error: %v", test.query, err)
+			continue
+		}
+		var got []string
+		for _, row := range rows {
+			if f.Match(row) {
+				got = append(got, row.ImportPath)
+			}
+		}
+		if !equal(got, test.want) {
+			t.Errorf("ParseFilter(%q): got %q, want %q", test.query, got, test.want)
+		}
+		if p := f.Prefix(); p != test.prefix {
+			t.Errorf("ParseFilter(%q) prefix: got %q, want %q", test.query, p, test.prefix)
+		}
+	}
+
+	for _, bad := range []string{":x", "path:", "color:red", "type:widget", "rank>high", "name>x"} {
+		if f, err := graph.ParseFilter(bad); err == nil {
+			t.Errorf("ParseFilter(%q): got %+v, want error", bad, f)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewBlob(memstore.New())
+	g := graph.New(st, &graph.Options{SearchIndex: true})
+
+	search := func(g *graph.Graph, prefix string) []string {
+		t.Helper()
+		var got []string
+		if err := g.Search(ctx, prefix, func(word, pkg string) error {
+			got = append(got, word+" "+pkg)
+			return nil
+		}); err != nil {
+			t.Fatalf("Search(%q): %v", prefix, err)
+		}
+		return got
+	}
+	check := func(g *graph.Graph, prefix string, want ...string) {
+		t.Helper()
+		if got := search(g, prefix); !equal(got, want) {
+			t.Errorf("Search(%q): got %q, want %q", prefix, got, want)
+		}
+	}
+
+	if got, want := graph.SearchWords(&graph.Row{
+		Name: "yaml", ImportPath: "gopkg.in/yaml.v2",
+	}), []string{"gopkg", "gopkg.in", "in", "v2", "yaml", "yaml.v2"}; !equal(got, want) {
+		t.Errorf("SearchWords: got %q, want %q", got, want)
+	}
+
+	a := pkg("x/net-http")
+	a.Name = "nethttp"
+	b := pkg("x/y/Net")
+	b.Name = "net"
+	if err := g.AddAll(ctx, newRepo("d1", a, b)); err != nil {
+		t.Fatalf("AddAll: %v", err)
+	}
+	check(g, "NET", "net x/net-http", "net x/y/Net", "net-http x/net-http", "nethttp x/net-http")
+	check(g, "http", "http x/net-http")
+
+	// Updating and removing rows updates the index.
+	b.Name = "other"
+	if err := g.AddAll(ctx, newRepo("d2", b)); err != nil {
+		t.Fatalf("AddAll: %v", err)
+	}
+	check(g, "other", "other x/y/Net")
+	if err := g.Remove(ctx, "x/net-http"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	check(g, "net", "net x/y/Net")
+
+	// A graph without an index can be indexed after the fact.
+	h := graph.New(st, nil).Tag("v1")
+	if err := h.PutRow(ctx, &graph.Row{Name: "p", ImportPath: "z/pdq"}); err != nil {
+		t.Fatalf("PutRow: %v", err)
+	}
+	check(h, "p")
+	h = graph.New(st, &graph.Options{SearchIndex: true}).Tag("v1")
+	if nr, err := h.Reindex(ctx); err != nil {
+		t.Fatalf("Reindex: %v", err)
+	} else if nr != 1 {
+		t.Errorf("Reindex: got %d rows, want 1", nr)
+	}
+	check(h, "p", "p z/pdq", "pdq z/pdq")
+	check(g, "pdq") // other views are unaffected
+
+	// Entries written while the index was enabled are removed when their rows
+	// change while it is disabled, but no new entries are written.
+	off := graph.New(st, nil)
+	b.Name = "renamed"
+	if err := off.AddAll(ctx, newRepo("d3", b)); err != nil {
+		t.Fatalf("AddAll: %v", err)
+	}
+	check(g, "other")
+	check(g, "renamed")
+	check(g, "net", "net x/y/Net")
+	if err := off.Remove(ctx, "x/y/Net"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	check(g, "net")
+}
+
+func TestIndexes(t *testing.T) {
+	ctx := context.Background()
+	g := graph.New(storage.NewBlob(memstore.New()), nil)
+
+	same := []byte("same")
+	put := func(pkg, repo string, digests ...[]byte) {
+		t.Helper()
+		row := &graph.Row{ImportPath: pkg, Repository: repo}
+		for i, d := range digests {
+			row.SourceFiles = append(row.SourceFiles, &graph.Row_File{
+				RepoPath: fmt.Sprintf("%s/f%d.go", pkg, i),
+				Digest:   d,
+			})
+		}
+		if err := g.PutRow(ctx, row); err != nil {
+			t.Fatalf("PutRow %q: %v", pkg, err)
+		}
+	}
+	checkRepo := func(start string, want []string, urls ...string) {
+		t.Helper()
+		got := scanKeys(t, func(f func(*graph.Row) error) error {
+			return g.ScanRepoRows(ctx, urls, start, f)
+		})
+		if !equal(got, want) {
+			t.Errorf("ScanRepoRows(%q, %q): got %q, want %q", urls, start, got, want)
+		}
+	}
+	checkDigests := func(want ...string) {
+		t.Helper()
+		var got []string
+		if err := g.ScanDigests(ctx, func(digest []byte, files []*graph.FileRef) error {
+			for _, f := range files {
+				got = append(got, string(digest)+" "+f.RepoPath)
+			}
+			return nil
+		}); err != nil {
+			t.Fatalf("ScanDigests: %v", err)
+		}
+		if !equal(got, want) {
+			t.Errorf("ScanDigests: got %q, want %q", got, want)
+		}
+	}
+
+	put("x/a", "https://github.com/X/y.git", same, []byte("a"))
+	put("x/b", "https://github.com/x/y", same)
+	put("z/c", "https://example.com/z")
+
+	// Before the indexes are marked complete, lookups scan the rows.
+	for i := 0; i < 2; i++ {
+		checkRepo("", []string{"x/a", "x/b"}, "https://github.com/x/y")
+		checkRepo("x/b", []string{"x/b"}, "github.com/x/y")
+		checkRepo("", []string{"x/a", "x/b", "z/c"}, "https://example.com/z/", "https://github.com/x/Y")
+		checkRepo("", nil, "https://example.com/nonesuch")
+		checkDigests("a x/a/f1.go", "same x/a/f0.go", "same x/b/f0.go")
+
+		if ok, err := g.Indexed(ctx); err != nil {
+			t.Fatalf("Indexed: %v", err)
+		} else if ok != (i > 0) {
+			t.Errorf("Indexed: got %v, want %v", ok, i > 0)
+		}
+		if nr, err := g.Reindex(ctx); err != nil {
+			t.Fatalf("Reindex: %v", err)
+		} else if nr != 3 {
+			t.Errorf("Reindex: got %d rows, want 3", nr)
+		}
+	}
+
+	// Updates to rows are reflected in the indexes.
+	put("x/b", "https://example.com/z", []byte("b"))
+	if err := g.Remove(ctx, "x/a"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	checkRepo("", nil, "https://github.com/x/y")
+	checkRepo("", []string{"x/b", "z/c"}, "https://example.com/z")
+	checkDigests("b x/b/f0.go")
+}
+
+// failStore wraps a storage.Interface so that writes to keys other than rows
+// fail while fail is set.
+type failStore struct {
+	storage.Interface
+	fail *bool
+}
+
+func (s failStore) Store(ctx context.Context, key string, val proto.Message) error {
+	if *s.fail && strings.HasPrefix(key, "\xff") {
+		return errors.New("injected failure")
+	}
+	return s.Interface.Store(ctx, key, val)
+}
+
+func TestIndexFailure(t *testing.T) {
+	ctx := context.Background()
+	fail := new(bool)
+	g := graph.New(failStore{Interface: storage.NewBlob(memstore.New()), fail: fail}, nil)
+
+	const url = "https://example.com/z"
+	checkIndexed := func(want bool) {
+		t.Helper()
+		if ok, err := g.Indexed(ctx); err != nil {
+			t.Fatalf("Indexed: %v", err)
+		} else if ok != want {
+			t.Errorf("Indexed: got %v, want %v", ok, want)
+		}
+	}
+	checkRepo := func(want ...string) {
+		t.Helper()
+		got := scanKeys(t, func(f func(*graph.Row) error) error {
+			return g.ScanRepoRows(ctx, []string{url}, "", f)
+		})
+		if !equal(got, want) {
+			t.Errorf("ScanRepoRows: got %q, want %q", got, want)
+		}
+	}
+
+	if err := g.PutRow(ctx, &graph.Row{ImportPath: "z/a", Repository: url}); err != nil {
+		t.Fatalf("PutRow: %v", err)
+	} else if _, err := g.Reindex(ctx); err != nil {
+		t.Fatalf("Reindex: %v", err)
+	}
+	checkIndexed(true)
+
+	// A row whose index entries could not be written is still found, since the
+	// indexes are no longer marked complete.
+	*fail = true
+	if err := g.PutRow(ctx, &graph.Row{ImportPath: "z/b", Repository: url}); err == nil {
+		t.Error("PutRow: got nil error, want failure")
+	}
+	*fail = false
+	checkIndexed(false)
+	checkRepo("z/a", "z/b")
+
+	if _, err := g.Reindex(ctx); err != nil {
+		t.Fatalf("Reindex: %v", err)
+	}
+	checkIndexed(true)
+	checkRepo("z/a", "z/b")
+}
@@ -0,0 +1,253 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/creachadair/repodeps/storage"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Versions of a row are stored under keys of the form
+//
+//    auxKey(histKind, <import-path> NUL <timestamp> NUL <digest>)
+//
+// where timestamp is the recording time in nanoseconds since the epoch, as 16
+// hex digits. Thus the versions of each row are contiguous, and sorted from
+// oldest to newest. In a tagged view, these keys have the prefix of the tag.
+
+func histPrefix(pkg string) string { return auxKey(histKind, pkg+"\x00") }
+
+func histKey(pkg string, when time.Time, digest string) string {
+	return histPrefix(pkg) + fmt.Sprintf("%016x", when.UnixNano()) + "\x00" + digest
+}
+
+// parseHistKey parses the name portion of a history key.
+func parseHistKey(name string) (pkg string, when time.Time, digest string, ok bool) {
+	parts := strings.SplitN(name, "\x00", 3)
+	if len(parts) != 3 {
+		return "", time.Time{}, "", false
+	}
+	ns, err := strconv.ParseInt(parts[1], 16, 64)
+	if err != nil {
+		return "", time.Time{}, "", false
+	}
+	return parts[0], time.Unix(0, ns), parts[2], true
+}
+
+// recordVersion adds v as the newest version of pkg, unless it is identical
+// to the current newest version, then discards versions that exceed the
+// retention limits.
+func (g *Graph) recordVersion(ctx context.Context, pkg string, v *Version) error {
+	var keys []string
+	pfx := g.key(histPrefix(pkg))
+	if err := g.st.Scan(ctx, pfx, func(key string) error {
+		if !strings.HasPrefix(key, pfx) {
+			return storage.ErrStopScan
+		}
+		keys = append(keys, key)
+		return nil
+	}); err != nil {
+		return err
+	}
+	if len(keys) == 0 && v.Removed {
+		return nil // no history to terminate
+	} else if len(keys) != 0 {
+		var last Version
+		if err := g.st.Load(ctx, keys[len(keys)-1], &last); err != nil {
+			return err
+		} else if last.Removed == v.Removed && last.Digest == v.Digest && proto.Equal(last.Row, v.Row) {
+			return nil // nothing has changed
+		}
+	}
+
+	now := time.Now()
+	v.When = timestamppb.New(now)
+	if err := g.st.Store(ctx, g.key(histKey(pkg, now, v.Digest)), v); err != nil {
+		return err
+	}
+
+	// Discard the oldest versions until the remainder satisfy the limits.
+	// The version just added is never discarded.
+	for i, key := range keys {
+		n := len(keys) - i + 1 // versions remaining, including the new one
+		_, when, _, _ := parseHistKey(strings.TrimPrefix(key, g.key(auxKey(histKind, ""))))
+		tooMany := g.opts.MaxVersions > 0 && n > g.opts.MaxVersions
+		tooOld := g.opts.MaxAge > 0 && now.Sub(when) > g.opts.MaxAge
+		if !tooMany && !tooOld {
+			break
+		} else if err := g.st.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Versions calls f with each recorded version of the row for pkg, from oldest
+// to newest. If f reports an error, scanning terminates. If the error is
+// storage.ErrStopScan, Versions returns nil. Otherwise Versions returns the
+// error from f.
+func (g *Graph) Versions(ctx context.Context, pkg string, f func(*Version) error) error {
+	pfx := g.key(histPrefix(pkg))
+	return g.st.Scan(ctx, pfx, func(key string) error {
+		if !strings.HasPrefix(key, pfx) {
+			return storage.ErrStopScan
+		}
+		var v Version
+		if err := g.st.Load(ctx, key, &v); err != nil {
+			return err
+		}
+		return f(&v)
+	})
+}
+
+// A Snapshot selects a historical view of the graph from its recorded
+// history. Each field that is set constrains the versions selected.
+type Snapshot struct {
+	// Select versions recorded at or before this time.
+	Time time.Time
+
+	// Select versions recorded from a repository at this commit digest (hex).
+	// A prefix of the digest is accepted.
+	Digest string
+}
+
+// IsZero reports whether s is the zero Snapshot, which selects the most
+// recent version of every row.
+func (s Snapshot) IsZero() bool { return s.Time.IsZero() && s.Digest == "" }
+
+func (s Snapshot) matches(when time.Time, digest string) bool {
+	return (s.Time.IsZero() || !when.After(s.Time)) &&
+		(s.Digest == "" || strings.HasPrefix(digest, s.Digest))
+}
+
+// ScanAt calls f with each row whose key is lexicographically greater than or
+// equal to start, as of the specified snapshot. The row for each package is
+// the newest version selected by the snapshot; packages with no selected
+// version, or whose selected version is a removal, are skipped. Only rows
+// with recorded history are visited.
+//
+// If f reports an error, scanning terminates. If the error is
+// storage.ErrStopScan, ScanAt returns nil. Otherwise ScanAt returns the error
+// from f.
+func (g *Graph) ScanAt(ctx context.Context, snap Snapshot, start string, f func(*Row) error) error {
+	pfx := g.key(auxKey(histKind, ""))
+	var curPkg, bestKey string
+	var stopped bool
+	flush := func() error {
+		if bestKey == "" {
+			return nil
+		}
+		var v Version
+		err := g.st.Load(ctx, bestKey, &v)
+		bestKey = ""
+		if err != nil {
+			return err
+		} else if v.Removed {
+			return nil
+		}
+		err = f(v.Row)
+		stopped = err == storage.ErrStopScan
+		return err
+	}
+	if err := g.st.Scan(ctx, pfx+start, func(key string) error {
+		if !strings.HasPrefix(key, pfx) {
+			return storage.ErrStopScan // no more history
+		}
+		pkg, when, digest, ok := parseHistKey(strings.TrimPrefix(key, pfx))
+		if !ok {
+			return nil // skip malformed keys
+		} else if pkg != curPkg {
+			if err := flush(); err != nil {
+				return err
+			}
+			curPkg = pkg
+		}
+		if snap.matches(when, digest) {
+			bestKey = key // versions are sorted oldest to newest
+		}
+		return nil
+	}); err != nil || stopped {
+		return err
+	}
+	if err := flush(); err != storage.ErrStopScan {
+		return err
+	}
+	return nil
+}
+
+// Changes calls f for each package whose newest recorded version is later
+// than since, in lexicographic order by import path. The removed flag reports
+// whether that version records the removal of the row. Only packages with
+// recorded history are visited.
+//
+// If f reports an error, scanning terminates. If the error is
+// storage.ErrStopScan, Changes returns nil. Otherwise Changes returns the
+// error from f.
+func (g *Graph) Changes(ctx context.Context, since time.Time, f func(pkg string, removed bool) error) error {
+	pfx := g.key(auxKey(histKind, ""))
+	var curPkg, lastKey string
+	var lastWhen time.Time
+	var stopped bool
+	flush := func() error {
+		if lastKey == "" || !lastWhen.After(since) {
+			return nil
+		}
+		var v Version
+		err := g.st.Load(ctx, lastKey, &v)
+		lastKey = ""
+		if err != nil {
+			return err
+		}
+		err = f(curPkg, v.Removed)
+		stopped = err == storage.ErrStopScan
+		return err
+	}
+	if err := g.st.Scan(ctx, pfx, func(key string) error {
+		if !strings.HasPrefix(key, pfx) {
+			return storage.ErrStopScan // no more history
+		}
+		pkg, when, _, ok := parseHistKey(strings.TrimPrefix(key, pfx))
+		if !ok {
+			return nil // skip malformed keys
+		} else if pkg != curPkg {
+			if err := flush(); err != nil {
+				return err
+			}
+			curPkg = pkg
+		}
+		lastKey, lastWhen = key, when // versions are sorted oldest to newest
+		return nil
+	}); err != nil || stopped {
+		return err
+	}
+	if err := flush(); err != storage.ErrStopScan {
+		return err
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler for a Version by delegating to protojson.
+func (v *Version) MarshalJSON() ([]byte, error) { return protojson.Marshal(v) }
+
+// UnmarshalJSON implements json.Unmarshaler for a Version by delegating to protojson.
+func (v *Version) UnmarshalJSON(data []byte) error { return protojson.Unmarshal(data, v) }
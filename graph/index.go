@@ -0,0 +1,333 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"bitbucket.org/creachadair/stringset"
+	"github.com/creachadair/repodeps/poll"
+	"github.com/creachadair/repodeps/storage"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Each view of the graph maintains secondary indexes of its rows, which are
+// updated whenever a row is stored or removed. Index entries have empty
+// values, and are stored under the keys
+//
+//	auxKey(byRepoKind, <canonical-repo-url> NUL <import-path>)
+//	auxKey(byDigestKind, <hex-digest> NUL <import-path> NUL <repo-path>)
+//
+// along with the search index entries (see SearchWords), if enabled. Rows
+// written before the indexes existed are not indexed, so the indexes are used
+// for lookups only after Reindex has recorded that they are complete.
+//
+// A row and its index entries are written separately, since the storage does
+// not support transactions. Updates to rows are serialized, so that each sees
+// the index entries of the previous one, and if an index update fails, the
+// indexes are marked incomplete so that lookups scan the rows instead. If the
+// process stops between writing a row and its index entries, the indexes may
+// be missing entries for that row; Reindex rebuilds them.
+//
+// Search entries are removed when they no longer match their row even while
+// the search index is disabled, since they may have been written while it was
+// enabled. Entries that still match are kept, so only the rows written while
+// the index was disabled are missing from it.
+
+func repoIndexKey(url, pkg string) string { return auxKey(byRepoKind, url+"\x00"+pkg) }
+
+func digestIndexKey(digest, pkg, path string) string {
+	return auxKey(byDigestKind, digest+"\x00"+pkg+"\x00"+path)
+}
+
+// indexKeys returns the keys of the index entries for row, including the
+// search entries whether or not the search index is enabled.
+func (g *Graph) indexKeys(row *Row) []string {
+	if row == nil {
+		return nil
+	}
+	var keys []string
+	if row.Repository != "" {
+		keys = append(keys, repoIndexKey(poll.CanonicalURL(row.Repository), row.ImportPath))
+	}
+	for _, src := range row.SourceFiles {
+		if len(src.Digest) != 0 {
+			keys = append(keys, digestIndexKey(hex.EncodeToString(src.Digest), row.ImportPath, src.RepoPath))
+		}
+	}
+	for _, word := range SearchWords(row) {
+		keys = append(keys, searchKey(word, row.ImportPath))
+	}
+	return keys
+}
+
+// storeRow stores row under its import path, and updates the index entries
+// for the row.
+func (g *Graph) storeRow(ctx context.Context, row *Row) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var old []string
+	if prev, err := g.Row(ctx, row.ImportPath); err == nil {
+		old = g.indexKeys(prev)
+	} else if err != storage.ErrKeyNotFound {
+		return err
+	}
+	if err := g.st.Store(ctx, g.key(row.ImportPath), row); err != nil {
+		return err
+	}
+	return g.checkIndex(ctx, g.updateIndex(ctx, old, g.indexKeys(row)))
+}
+
+// deleteRow removes the row for pkg, and removes the index entries for the
+// row.
+func (g *Graph) deleteRow(ctx context.Context, pkg string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var old []string
+	if row, err := g.Row(ctx, pkg); err == nil {
+		old = g.indexKeys(row)
+	}
+	if err := g.st.Delete(ctx, g.key(pkg)); err != nil {
+		return err
+	}
+	return g.checkIndex(ctx, g.updateIndex(ctx, old, nil))
+}
+
+// checkIndex returns err. If err != nil, it first discards the record that the
+// indexes of g are complete, if possible, since the failed update may have
+// left them inconsistent with the rows.
+func (g *Graph) checkIndex(ctx context.Context, err error) error {
+	if err != nil {
+		g.st.Delete(ctx, g.key(auxKey(indexKind, ""))) // best-effort
+	}
+	return err
+}
+
+// updateIndex replaces the index entries with the old keys by entries with
+// the current keys. New search entries are stored only if the search index is
+// enabled.
+func (g *Graph) updateIndex(ctx context.Context, old, cur []string) error {
+	keep := stringset.New(cur...)
+	for _, key := range old {
+		if keep.Contains(key) {
+			keep.Discard(key) // already present
+		} else if err := g.st.Delete(ctx, g.key(key)); err != nil && err != storage.ErrKeyNotFound {
+			return err
+		}
+	}
+	for key := range keep {
+		if !g.opts.SearchIndex && strings.HasPrefix(key, auxKey(searchKind, "")) {
+			continue // not maintained
+		} else if err := g.st.Store(ctx, g.key(key), new(emptypb.Empty)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Indexed reports whether the indexes of g are complete, having been built by
+// Reindex. Until then, lookups that would use the indexes scan the rows.
+func (g *Graph) Indexed(ctx context.Context) (bool, error) {
+	err := g.st.Load(ctx, g.key(auxKey(indexKind, "")), new(emptypb.Empty))
+	if err == storage.ErrKeyNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Reindex discards the indexes of g and rebuilds them from the current rows,
+// then records that they are complete. It reports the number of rows indexed.
+// This is necessary to index rows written before the indexes existed, or
+// before the search index was enabled, or to repair them after an interrupted
+// update. Updates to rows in any view of the graph wait until it is done.
+func (g *Graph) Reindex(ctx context.Context) (int, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, kind := range []string{byRepoKind, byDigestKind, searchKind} {
+		pfx := g.key(auxKey(kind, ""))
+		var stale []string
+		if err := g.st.Scan(ctx, pfx, func(key string) error {
+			if !strings.HasPrefix(key, pfx) {
+				return storage.ErrStopScan
+			}
+			stale = append(stale, key)
+			return nil
+		}); err != nil {
+			return 0, err
+		}
+		for _, key := range stale {
+			if err := g.st.Delete(ctx, key); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	var nr int
+	if err := g.Scan(ctx, "", func(row *Row) error {
+		nr++
+		return g.updateIndex(ctx, nil, g.indexKeys(row))
+	}); err != nil {
+		return 0, err
+	}
+	return nr, g.st.Store(ctx, g.key(auxKey(indexKind, "")), new(emptypb.Empty))
+}
+
+// ScanRepoRows calls f with each row of g whose import path is greater than
+// or equal to start and whose repository URL has the same canonical form as
+// one of urls, in order by import path. It uses the repository index if g is
+// indexed, and otherwise scans all the rows.
+//
+// If f reports an error, scanning terminates. If the error is
+// storage.ErrStopScan, ScanRepoRows returns nil. Otherwise ScanRepoRows
+// returns the error from f.
+func (g *Graph) ScanRepoRows(ctx context.Context, urls []string, start string, f func(*Row) error) error {
+	want := stringset.FromIndexed(len(urls), func(i int) string { return poll.CanonicalURL(urls[i]) })
+	match := func(row *Row) error {
+		if row.Repository == "" || !want.Contains(poll.CanonicalURL(row.Repository)) {
+			return nil
+		}
+		return f(row)
+	}
+	if ok, err := g.Indexed(ctx); err != nil {
+		return err
+	} else if !ok {
+		return g.Scan(ctx, start, match)
+	}
+
+	pkgs := stringset.New()
+	for url := range want {
+		pfx := g.key(repoIndexKey(url, ""))
+		if err := g.st.Scan(ctx, pfx+start, func(key string) error {
+			if !strings.HasPrefix(key, pfx) {
+				return storage.ErrStopScan
+			}
+			pkgs.Add(strings.TrimPrefix(key, pfx))
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	for _, pkg := range pkgs.Elements() {
+		row, err := g.Row(ctx, pkg)
+		if err == storage.ErrKeyNotFound {
+			continue // stale index entry
+		} else if err != nil {
+			return err
+		} else if err := match(row); err == storage.ErrStopScan {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// A FileRef identifies a source file of a package.
+type FileRef struct {
+	ImportPath string `json:"importPath"`
+	RepoPath   string `json:"repoPath"`
+}
+
+// ScanDigests calls f with each distinct source file digest in g, together
+// with the files having that digest, in order by digest. It uses the digest
+// index if g is indexed, and otherwise scans all the rows.
+//
+// If f reports an error, scanning terminates. If the error is
+// storage.ErrStopScan, ScanDigests returns nil. Otherwise ScanDigests returns
+// the error from f.
+func (g *Graph) ScanDigests(ctx context.Context, f func(digest []byte, files []*FileRef) error) error {
+	ok, err := g.Indexed(ctx)
+	if err != nil {
+		return err
+	} else if !ok {
+		return g.scanDigestsSlow(ctx, f)
+	}
+
+	var cur []byte
+	var files []*FileRef
+	var stopped bool
+	flush := func() error {
+		if len(files) == 0 {
+			return nil
+		}
+		err := f(cur, files)
+		stopped = err == storage.ErrStopScan
+		files = nil
+		return err
+	}
+	pfx := g.key(auxKey(byDigestKind, ""))
+	if err := g.st.Scan(ctx, pfx, func(key string) error {
+		if !strings.HasPrefix(key, pfx) {
+			return storage.ErrStopScan
+		}
+		parts := strings.SplitN(strings.TrimPrefix(key, pfx), "\x00", 3)
+		if len(parts) != 3 {
+			return nil // skip malformed keys
+		}
+		digest, err := hex.DecodeString(parts[0])
+		if err != nil {
+			return nil // skip malformed keys
+		} else if !bytes.Equal(digest, cur) {
+			if err := flush(); err != nil {
+				return err
+			}
+			cur = digest
+		}
+		files = append(files, &FileRef{ImportPath: parts[1], RepoPath: parts[2]})
+		return nil
+	}); err != nil || stopped {
+		return err
+	}
+	if err := flush(); err != storage.ErrStopScan {
+		return err
+	}
+	return nil
+}
+
+// scanDigestsSlow implements ScanDigests by scanning all the rows of g.
+func (g *Graph) scanDigestsSlow(ctx context.Context, f func(digest []byte, files []*FileRef) error) error {
+	byDigest := make(map[string][]*FileRef)
+	if err := g.Scan(ctx, "", func(row *Row) error {
+		for _, src := range row.SourceFiles {
+			if len(src.Digest) != 0 {
+				key := string(src.Digest)
+				byDigest[key] = append(byDigest[key], &FileRef{
+					ImportPath: row.ImportPath,
+					RepoPath:   src.RepoPath,
+				})
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	digests := make([]string, 0, len(byDigest))
+	for key := range byDigest {
+		digests = append(digests, key)
+	}
+	sort.Strings(digests) // byte order, consistent with the index
+	for _, key := range digests {
+		if err := f([]byte(key), byDigest[key]); err == storage.ErrStopScan {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
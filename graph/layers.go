@@ -0,0 +1,106 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import "sort"
+
+// A Layering is a topological layering of a directed graph.
+type Layering struct {
+	// Each node in Layers[i] depends only on nodes in Layers[j] for j < i.
+	// Thus the nodes of layer 0 have no dependencies.
+	Layers [][]string `json:"layers,omitempty"`
+
+	// Strongly-connected components with more than one node, or a dependency
+	// on themselves, that prevent their members from being layered.
+	Cycles [][]string `json:"cycles,omitempty"`
+
+	// Nodes that are not in a cycle, but cannot be layered because they
+	// depend (directly or indirectly) on a node that is.
+	Blocked []string `json:"blocked,omitempty"`
+}
+
+// Layer computes a topological layering of the directed graph whose nodes are
+// the keys of adj, where adj[v] lists the nodes that v depends on. Edges to
+// nodes that are not keys of adj are ignored. Each layer, cycle, and the
+// blocked list are ordered by name, and cycles are ordered by their first
+// member.
+func Layer(adj map[string][]string) *Layering {
+	// Count the distinct in-set dependencies of each node, and record the
+	// reverse edges so that completing a node can release its dependents.
+	pending := make(map[string]int)
+	rev := make(map[string][]string)
+	for v, deps := range adj {
+		seen := make(map[string]bool)
+		for _, w := range deps {
+			if _, ok := adj[w]; ok && !seen[w] {
+				seen[w] = true
+				pending[v]++
+				rev[w] = append(rev[w], v)
+			}
+		}
+	}
+
+	out := new(Layering)
+	var cur []string
+	for v := range adj {
+		if pending[v] == 0 {
+			cur = append(cur, v)
+		}
+	}
+	done := 0
+	for len(cur) != 0 {
+		sort.Strings(cur)
+		out.Layers = append(out.Layers, cur)
+		done += len(cur)
+		var next []string
+		for _, w := range cur {
+			for _, v := range rev[w] {
+				pending[v]--
+				if pending[v] == 0 {
+					next = append(next, v)
+				}
+			}
+		}
+		cur = next
+	}
+	if done == len(adj) {
+		return out
+	}
+
+	// The remaining nodes are either in a cycle or depend on one.
+	rest := make(map[string]map[string]bool)
+	for v, n := range pending {
+		if n == 0 {
+			continue
+		}
+		rest[v] = make(map[string]bool)
+		for _, w := range adj[v] {
+			if pending[w] != 0 {
+				rest[v][w] = true
+			}
+		}
+	}
+	for _, comp := range components(rest) {
+		if len(comp) > 1 || rest[comp[0]][comp[0]] {
+			sort.Strings(comp)
+			out.Cycles = append(out.Cycles, comp)
+		} else {
+			out.Blocked = append(out.Blocked, comp[0])
+		}
+	}
+	sort.Slice(out.Cycles, func(i, j int) bool { return out.Cycles[i][0] < out.Cycles[j][0] })
+	sort.Strings(out.Blocked)
+	return out
+}
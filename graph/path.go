@@ -0,0 +1,225 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/creachadair/repodeps/storage"
+)
+
+// PathOptions control the search performed by Paths. A nil *PathOptions
+// provides default values.
+type PathOptions struct {
+	// The maximum number of paths to report; 0 means 1.
+	Limit int
+
+	// If set, do not traverse any package for which Skip reports true. The row
+	// is nil if the package has no row in the graph. Sources and the target
+	// are never skipped.
+	Skip func(pkg string, row *Row) bool
+}
+
+func (o *PathOptions) limit() int {
+	if o == nil || o.Limit <= 0 {
+		return 1
+	}
+	return o.Limit
+}
+
+// Paths finds the shortest import chains from any of the source packages to
+// the target package, following direct dependencies. Each chain begins with a
+// source package and ends with the target. Up to opts.Limit chains are
+// reported, in order of nondecreasing length; the chains do not repeat any
+// package. If there is no chain, Paths returns nil without error.
+func (g *Graph) Paths(ctx context.Context, sources []string, target string, opts *PathOptions) ([][]string, error) {
+	s := &pathSearch{
+		ctx:    ctx,
+		g:      g,
+		opts:   opts,
+		target: target,
+		srcs:   sources,
+		cache:  make(map[string][]string),
+		rows:   make(map[string]*Row),
+	}
+	return s.kShortest(opts.limit())
+}
+
+// A pathSearch holds the state of a search for paths to a target. The empty
+// string is used as a virtual root whose successors are the sources, so that
+// a search from multiple sources is a search from a single node.
+type pathSearch struct {
+	ctx    context.Context
+	g      *Graph
+	opts   *PathOptions
+	target string
+	srcs   []string
+	cache  map[string][]string // :: package → traversable successors
+	rows   map[string]*Row     // :: package → row, or nil if none
+	err    error
+}
+
+// next returns the successors of pkg that the search may traverse.
+func (s *pathSearch) next(pkg string) []string {
+	if pkg == "" {
+		return s.srcs
+	} else if succ, ok := s.cache[pkg]; ok {
+		return succ
+	}
+	var succ []string
+	if row := s.rowOf(pkg); row != nil {
+		for _, dep := range row.Directs {
+			if dep == s.target || !s.skip(dep) {
+				succ = append(succ, dep)
+			}
+		}
+	}
+	s.cache[pkg] = succ
+	return succ
+}
+
+// skip reports whether the search must not traverse pkg.
+func (s *pathSearch) skip(pkg string) bool {
+	return s.opts != nil && s.opts.Skip != nil && s.opts.Skip(pkg, s.rowOf(pkg))
+}
+
+// rowOf returns the row for pkg, or nil if it has none.
+func (s *pathSearch) rowOf(pkg string) *Row {
+	if row, ok := s.rows[pkg]; ok {
+		return row
+	}
+	row, err := s.g.Row(s.ctx, pkg)
+	if err != nil {
+		if err != storage.ErrKeyNotFound && s.err == nil {
+			s.err = err
+		}
+		row = nil
+	}
+	s.rows[pkg] = row
+	return row
+}
+
+// shortest returns the shortest path from start to the target that does not
+// visit any banned node or traverse any banned edge, or nil if none exists.
+// Ties are broken by preferring lexicographically smaller successors.
+func (s *pathSearch) shortest(start string, nodes map[string]bool, edges map[[2]string]bool) []string {
+	parent := map[string]string{start: start}
+	queue := []string{start}
+	for len(queue) != 0 && s.err == nil {
+		cur := queue[0]
+		queue = queue[1:]
+		if cur == s.target {
+			var path []string
+			for p := cur; ; p = parent[p] {
+				path = append(path, p)
+				if p == start {
+					break
+				}
+			}
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			return path
+		}
+		succ := append([]string(nil), s.next(cur)...)
+		sort.Strings(succ)
+		for _, nxt := range succ {
+			if _, seen := parent[nxt]; seen || nodes[nxt] || edges[[2]string{cur, nxt}] {
+				continue
+			}
+			parent[nxt] = cur
+			queue = append(queue, nxt)
+		}
+	}
+	return nil
+}
+
+// kShortest finds up to k shortest loopless paths from the virtual root to
+// the target using Yen's algorithm, and returns them without the root.
+func (s *pathSearch) kShortest(k int) ([][]string, error) {
+	first := s.shortest("", nil, nil)
+	if first == nil {
+		return nil, s.err
+	}
+	found := [][]string{first}
+	var cands [][]string
+	for len(found) < k {
+		prev := found[len(found)-1]
+		for i := 0; i < len(prev)-1; i++ {
+			spur, root := prev[i], prev[:i+1]
+
+			// Ban the edges leaving the root that earlier paths have used, and
+			// the nodes of the root other than the spur.
+			edges := make(map[[2]string]bool)
+			for _, p := range found {
+				if len(p) > i+1 && equalPaths(p[:i+1], root) {
+					edges[[2]string{p[i], p[i+1]}] = true
+				}
+			}
+			nodes := make(map[string]bool)
+			for _, n := range root[:i] {
+				nodes[n] = true
+			}
+			tail := s.shortest(spur, nodes, edges)
+			if s.err != nil {
+				return nil, s.err
+			} else if tail == nil {
+				continue
+			}
+			path := append(append([]string(nil), root[:i]...), tail...)
+			if !containsPath(cands, path) && !containsPath(found, path) {
+				cands = append(cands, path)
+			}
+		}
+		if len(cands) == 0 {
+			break
+		}
+		sort.Slice(cands, func(i, j int) bool {
+			if len(cands[i]) == len(cands[j]) {
+				return strings.Join(cands[i], "\x00") < strings.Join(cands[j], "\x00")
+			}
+			return len(cands[i]) < len(cands[j])
+		})
+		found = append(found, cands[0])
+		cands = cands[1:]
+	}
+	for i, p := range found {
+		found[i] = p[1:] // drop the virtual root
+	}
+	return found, nil
+}
+
+func equalPaths(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func containsPath(ps [][]string, p []string) bool {
+	for _, q := range ps {
+		if equalPaths(p, q) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,18 +3,27 @@ package graph
 import (
 	"context"
 	"encoding/hex"
+	"fmt"
 	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
 
 	"bitbucket.org/creachadair/stringset"
 	"github.com/cayleygraph/quad"
 	"github.com/cayleygraph/quad/nquads"
 	"github.com/cayleygraph/quad/voc/rdf"
+	"github.com/creachadair/repodeps/storage"
 )
 
 const (
 	typePackage = quad.IRI("dep:Package")
 	typeRepo    = quad.IRI("dep:Repo")
 	typeFile    = quad.IRI("dep:File")
+	typeModule  = quad.IRI("dep:Module")
+	typeDep     = quad.IRI("dep:Dependency")
 
 	relType       = quad.IRI(rdf.Type)
 	relDefinedIn  = quad.IRI("dep:defined-in")  // package DefinedIn repo
@@ -26,18 +35,110 @@ const (
 	relImportPath = quad.IRI("dep:import-path") // package ImportPath <string>
 	relRepoURL    = quad.IRI("dep:repo-url")    // repo RepoURL <string>
 	relMissing    = quad.IRI("dep:is-missing")  // package Missing <bool>
+	relRemoved    = quad.IRI("dep:is-removed")  // package Removed <bool>
+	relModulePath = quad.IRI("dep:module-path") // package|module ModulePath <string>
+	relName       = quad.IRI("dep:name")        // package Name <string>
+	relPkgType    = quad.IRI("dep:pkg-type")    // package PkgType <string>
+	relNumPkgs    = quad.IRI("dep:packages")    // repo|module Packages <int>
+	relSource     = quad.IRI("dep:source")      // dependency Source repo|module
+	relTarget     = quad.IRI("dep:target")      // dependency Target repo|module
+	relWeight     = quad.IRI("dep:weight")      // dependency Weight <int>
 )
 
+// QuadOptions control the encoding performed by EncodeToQuads. A nil
+// *QuadOptions provides default values.
+type QuadOptions struct {
+	// If true, identify packages and files by IRIs derived from their import
+	// paths, digests, and repository paths, rather than by blank nodes, so
+	// that each node has the same identifier in every export. Repositories
+	// are always identified by their URLs.
+	StableIRIs bool
+
+	// If set, encode only the rows that have changed since this time according
+	// to the recorded history of the graph, so that the output can be applied
+	// to an earlier export. This implies StableIRIs. A package whose row was
+	// removed is encoded only by its import path and dep:is-removed. Rows
+	// without recorded history are never encoded.
+	//
+	// The output describes each changed package completely; ReplaceQuads
+	// applies it to a store holding an earlier export. Changes
+	// that do not record history, such as rankings assigned by a Rank or
+	// Impact update, are not included; a full export is needed to refresh
+	// them.
+	Since time.Time
+
+	// If set, encode only the packages with these import paths, as Since
+	// does for changed rows. A package without a row is encoded as removed.
+	// This implies StableIRIs, and is ignored if Since is set.
+	Packages []string
+}
+
+func (o *QuadOptions) stable() bool {
+	return o != nil && (o.StableIRIs || !o.Since.IsZero() || len(o.Packages) != 0)
+}
+
+func (o *QuadOptions) packages() []string {
+	if o == nil {
+		return nil
+	}
+	return o.Packages
+}
+
+func (o *QuadOptions) since() time.Time {
+	if o == nil {
+		return time.Time{}
+	}
+	return o.Since
+}
+
+// PackageIRI returns the stable IRI of the package with the given import path.
+func PackageIRI(pkg string) quad.IRI { return quad.IRI("dep:pkg/" + escapeIRI(pkg)) }
+
+// FileIRI returns the stable IRI of the file with the given hex digest and
+// repository path. The digest may be empty, if file digests were not
+// recorded.
+func FileIRI(digest, repoPath string) quad.IRI {
+	return quad.IRI("dep:file/" + digest + "/" + escapeIRI(repoPath))
+}
+
+// packageOfIRI reports the import path identified by v, if v is a stable
+// package IRI as constructed by PackageIRI.
+func packageOfIRI(v quad.Value) (string, bool) {
+	iri, ok := v.(quad.IRI)
+	if !ok || !strings.HasPrefix(string(iri), "dep:pkg/") {
+		return "", false
+	}
+	pkg, err := url.PathUnescape(strings.TrimPrefix(string(iri), "dep:pkg/"))
+	return pkg, err == nil && pkg != ""
+}
+
+// escapeIRI percent-encodes the characters of s that may not appear in an
+// IRI reference, along with the percent sign itself.
+func escapeIRI(s string) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c <= ' ' || strings.IndexByte(`<>"{}|^`+"`"+`\%`, c) >= 0 {
+			fmt.Fprintf(&buf, "%%%02X", c)
+		} else {
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
+
 // WriteQuads converts g to RDF 1.1 N-quads and writes them to w.
-func (g *Graph) WriteQuads(ctx context.Context, w io.Writer) error {
+func (g *Graph) WriteQuads(ctx context.Context, w io.Writer, opts *QuadOptions) error {
 	qw := nquads.NewWriter(w)
-	return g.EncodeToQuads(ctx, qw.WriteQuad)
+	if err := g.EncodeToQuads(ctx, opts, qw.WriteQuad); err != nil {
+		return err
+	}
+	return qw.Close() // flush buffered output
 }
 
 // EncodeToQuads converts g to RDF 1.1 N-quads and calls f for each. If f
 // reports an error the conversion is terminated and the error is returned to
 // the caller of EncodeToQuads.
-func (g *Graph) EncodeToQuads(ctx context.Context, f func(quad.Quad) error) (err error) {
+func (g *Graph) EncodeToQuads(ctx context.Context, opts *QuadOptions, f func(quad.Quad) error) (err error) {
 	defer func() {
 		if v := recover(); v != nil {
 			if e, ok := v.(error); ok {
@@ -65,28 +166,44 @@ func (g *Graph) EncodeToQuads(ctx context.Context, f func(quad.Quad) error) (err
 		}
 		return b
 	}
-	pkgs := make(map[string]quad.BNode) // :: import path → BNode
-	shas := make(map[string]quad.BNode) // :: digest → BNode
+	pkgs := make(map[string]quad.BNode)  // :: import path → BNode
+	files := make(map[string]quad.BNode) // :: digest NUL repo path → BNode
 	defn := stringset.New()
 	need := stringset.New()
 
-	P := func(pkg string) quad.BNode { return assign(pkgs, pkg) }
-	F := func(sha string) quad.BNode { return assign(shas, sha) }
+	P := func(pkg string) quad.Value { return assign(pkgs, pkg) }
+
+	// Files are identified by digest and path together, since distinct files
+	// may have the same contents, and digests may not be recorded at all.
+	F := func(sha, path string) quad.Value { return assign(files, sha+"\x00"+path) }
 	R := func(url string) quad.IRI { return quad.IRI(url) }
+	if opts.stable() {
+		P = func(pkg string) quad.Value { return PackageIRI(pkg) }
+		F = func(sha, path string) quad.Value { return FileIRI(sha, path) }
+	}
 
-	if err := g.Scan(ctx, "", func(row *Row) error {
+	encode := func(row *Row) error {
 		pid := P(row.ImportPath)
 		send(pid, relType, typePackage)
 		send(pid, relRanking, quad.Float(row.Ranking))
 		send(pid, relImportPath, quad.String(row.ImportPath))
+		if row.Name != "" {
+			send(pid, relName, quad.String(row.Name))
+		}
+		if row.Type != Row_UNKNOWN {
+			send(pid, relPkgType, quad.String(row.Type.String()))
+		}
+		if row.Module != "" {
+			send(pid, relModulePath, quad.String(row.Module))
+		}
 		defn.Add(row.ImportPath)
 		need.Discard(row.ImportPath)
 
 		send(R(row.Repository), relType, typeRepo)
-		send(P(row.ImportPath), relDefinedIn, R(row.Repository))
+		send(pid, relDefinedIn, R(row.Repository))
 
 		for _, pkg := range row.Directs {
-			send(P(row.ImportPath), relImports, P(pkg))
+			send(pid, relImports, P(pkg))
 			if !defn.Contains(pkg) {
 				need.Add(pkg)
 			}
@@ -94,23 +211,346 @@ func (g *Graph) EncodeToQuads(ctx context.Context, f func(quad.Quad) error) (err
 
 		for _, src := range row.SourceFiles {
 			hd := hex.EncodeToString(src.Digest)
-			send(F(hd), relType, typeFile)
-			send(F(hd), relDigest, quad.String(hd))
-			send(F(hd), relRepoPath, quad.String(src.RepoPath))
-			send(P(row.ImportPath), relHasFile, F(hd))
+			fid := F(hd, src.RepoPath)
+			send(fid, relType, typeFile)
+			send(fid, relDigest, quad.String(hd))
+			send(fid, relRepoPath, quad.String(src.RepoPath))
+			send(pid, relHasFile, fid)
 		}
 		return nil
-	}); err != nil {
+	}
+
+	// changed encodes the current state of pkg, which has changed.
+	changed := func(pkg string, removed bool) error {
+		if removed {
+			send(P(pkg), relType, typePackage)
+			send(P(pkg), relImportPath, quad.String(pkg))
+			send(P(pkg), relRemoved, quad.Bool(true))
+			defn.Add(pkg)
+			return nil
+		}
+		row, err := g.Row(ctx, pkg)
+		if err != nil {
+			return err
+		}
+		return encode(row)
+	}
+
+	since, only := opts.since(), opts.packages()
+	if !since.IsZero() || len(only) != 0 {
+		if !since.IsZero() {
+			if err := g.Changes(ctx, since, changed); err != nil {
+				return err
+			}
+		} else {
+			for _, pkg := range only {
+				row, err := g.Row(ctx, pkg)
+				if err == storage.ErrKeyNotFound {
+					err = changed(pkg, true)
+				} else if err == nil {
+					err = encode(row)
+				}
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// Unchanged dependencies that still have rows were encoded by an
+		// earlier export, so they are not missing.
+		for pkg := range need.Diff(defn) {
+			if _, err := g.Row(ctx, pkg); err == nil {
+				defn.Add(pkg)
+			} else if err != storage.ErrKeyNotFound {
+				return err
+			}
+		}
+	} else if err := g.Scan(ctx, "", encode); err != nil {
 		return err
 	}
 
 	// If any packages were depended upon but not mentioned in the graph, emit
 	// dummy rows for them.
 	for pkg := range need.Diff(defn) {
-		if _, ok := pkgs[pkg]; !ok {
-			send(P(pkg), relType, typePackage)
-			send(P(pkg), relMissing, quad.Bool(true))
+		send(P(pkg), relType, typePackage)
+		send(P(pkg), relImportPath, quad.String(pkg))
+		send(P(pkg), relMissing, quad.Bool(true))
+	}
+	return nil
+}
+
+// WriteAggregateQuads converts an aggregated graph to RDF 1.1 N-quads and
+// writes them to w. The level must be the one used to construct nodes.
+func WriteAggregateQuads(w io.Writer, level Level, nodes []*Node) error {
+	qw := nquads.NewWriter(w)
+	if err := EncodeAggregateToQuads(level, nodes, qw.WriteQuad); err != nil {
+		return err
+	}
+	return qw.Close() // flush buffered output
+}
+
+// EncodeAggregateToQuads converts an aggregated graph to RDF 1.1 N-quads and
+// calls f for each. Each weighted edge is represented by a dep:Dependency
+// node linking its source and target. If f reports an error the conversion
+// is terminated and the error is returned to the caller.
+func EncodeAggregateToQuads(level Level, nodes []*Node, f func(quad.Quad) error) error {
+	type po struct{ p, o quad.Value }
+	send := func(s quad.Value, pos ...po) error {
+		for _, v := range pos {
+			if err := f(quad.Quad{Subject: s, Predicate: v.p, Object: v.o}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var seq quad.Sequence
+	mods := make(map[string]quad.BNode) // :: module path → BNode
+	N := func(name string) quad.Value {
+		if level == ByRepository {
+			return quad.IRI(name)
+		}
+		b, ok := mods[name]
+		if !ok {
+			b = seq.Next()
+			mods[name] = b
+		}
+		return b
+	}
+
+	for _, n := range nodes {
+		desc := []po{{relType, typeRepo}, {relRepoURL, quad.String(n.Name)}}
+		if level == ByModule {
+			desc = []po{{relType, typeModule}, {relModulePath, quad.String(n.Name)}}
+		}
+		desc = append(desc, po{relNumPkgs, quad.Int(n.Packages)})
+		if err := send(N(n.Name), desc...); err != nil {
+			return err
+		}
+		for _, e := range n.Edges {
+			if err := send(seq.Next(),
+				po{relType, typeDep},
+				po{relSource, N(n.Name)},
+				po{relTarget, N(e.Target)},
+				po{relWeight, quad.Int(e.Weight)},
+			); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
+
+// ReadQuads reads RDF 1.1 N-quads in the vocabulary of EncodeToQuads from r,
+// and calls f with each row they describe. See QuadDecoder.
+func ReadQuads(r io.Reader, f func(*Row) error) error {
+	dec := NewQuadDecoder()
+	if err := dec.Read(r); err != nil {
+		return err
+	}
+	return dec.Rows(f)
+}
+
+// A QuadDecoder reconstructs rows from quads in the vocabulary of
+// EncodeToQuads. Since the quads describing a row may arrive in any order,
+// the decoder retains all the quads it is given until Rows is called.
+//
+// Packages marked dep:is-missing are not reported as rows, but may be the
+// targets of dep:imports. Packages marked dep:is-removed are not reported; use
+// Removed to find them. Each file node has a single repository path, but
+// older encodings identified files by digest alone, so if one file node has
+// several repository paths, each package is given the path whose directory
+// best matches its import path. A package that refers to such a node more
+// than once cannot be decoded, since its files cannot be told apart.
+type QuadDecoder struct {
+	nodes map[quad.Value]*quadNode
+}
+
+// NewQuadDecoder constructs a new empty decoder.
+func NewQuadDecoder() *QuadDecoder {
+	return &QuadDecoder{nodes: make(map[quad.Value]*quadNode)}
+}
+
+// A quadNode collects the properties of a single subject.
+type quadNode struct {
+	isPackage bool
+	missing   bool
+	removed   bool
+	path      string // import path of a package
+	name      string
+	pkgType   string
+	module    string
+	ranking   float64
+	repo      string
+	hasRepo   bool
+	imports   []quad.Value
+	files     []quad.Value
+
+	digest    string   // hex digest of a file
+	repoPaths []string // repository paths of a file
+}
+
+// Add adds q to the decoder. Quads outside the vocabulary are ignored.
+func (d *QuadDecoder) Add(q quad.Quad) {
+	n := d.nodes[q.Subject]
+	if n == nil {
+		n = new(quadNode)
+		d.nodes[q.Subject] = n
+	}
+	str := func() string { s, _ := quad.NativeOf(q.Object).(string); return s }
+	switch q.Predicate {
+	case relType:
+		n.isPackage = n.isPackage || q.Object == typePackage
+	case relMissing:
+		n.missing, _ = quad.NativeOf(q.Object).(bool)
+	case relRemoved:
+		n.removed, _ = quad.NativeOf(q.Object).(bool)
+	case relImportPath:
+		n.path = str()
+	case relName:
+		n.name = str()
+	case relPkgType:
+		n.pkgType = str()
+	case relModulePath:
+		n.module = str()
+	case relRanking:
+		switch v := quad.NativeOf(q.Object).(type) {
+		case float64:
+			n.ranking = v
+		case int64:
+			n.ranking = float64(v)
+		}
+	case relDefinedIn:
+		if iri, ok := q.Object.(quad.IRI); ok {
+			n.repo, n.hasRepo = string(iri), true
+		}
+	case relImports:
+		n.imports = append(n.imports, q.Object)
+	case relHasFile:
+		n.files = append(n.files, q.Object)
+	case relDigest:
+		n.digest = str()
+	case relRepoPath:
+		n.repoPaths = append(n.repoPaths, str())
+	}
+}
+
+// Read adds to d each of the RDF 1.1 N-quads read from r.
+func (d *QuadDecoder) Read(r io.Reader) error {
+	qr := nquads.NewReader(r, false)
+	for {
+		q, err := qr.ReadQuad()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		d.Add(q)
+	}
+}
+
+// Rows calls f with each row described by the quads added to d, in order by
+// import path. It reports an error without calling f if a package lacks an
+// import path or repository, or imports a node that is not a package with an
+// import path. An imported package identified by a stable IRI need not be
+// described by the quads, as in the output of an incremental export. If f
+// reports an error, Rows stops and returns that error.
+func (d *QuadDecoder) Rows(f func(*Row) error) error {
+	var rows []*Row
+	for id, n := range d.nodes {
+		if !n.isPackage || n.missing || n.removed {
+			continue
+		} else if n.path == "" {
+			return fmt.Errorf("package node %v has no import path", id)
+		} else if !n.hasRepo {
+			return fmt.Errorf("package %q has no repository", n.path)
+		}
+		row := &Row{
+			Name:       n.name,
+			ImportPath: n.path,
+			Repository: n.repo,
+			Type:       Row_Type(Row_Type_value[n.pkgType]),
+			Ranking:    n.ranking,
+			Module:     n.module,
+		}
+		seen := stringset.New()
+		for _, imp := range n.imports {
+			path, ok := packageOfIRI(imp)
+			if dep := d.nodes[imp]; dep != nil && dep.isPackage && dep.path != "" {
+				path, ok = dep.path, true
+			}
+			if !ok {
+				return fmt.Errorf("package %q imports unknown node %v", n.path, imp)
+			} else if !seen.Contains(path) {
+				seen.Add(path)
+				row.Directs = append(row.Directs, path)
+			}
+		}
+		seenFiles := make(map[quad.Value]bool)
+		for _, id := range n.files {
+			file := d.nodes[id]
+			if file == nil || len(file.repoPaths) == 0 {
+				return fmt.Errorf("package %q has unknown file %v", n.path, id)
+			} else if seenFiles[id] {
+				if len(file.repoPaths) > 1 {
+					return fmt.Errorf("package %q has indistinguishable files %v", n.path, id)
+				}
+				continue // a repeated quad
+			}
+			seenFiles[id] = true
+			digest, err := hex.DecodeString(file.digest)
+			if err != nil {
+				return fmt.Errorf("package %q file %v: invalid digest: %v", n.path, id, err)
+			}
+			row.SourceFiles = append(row.SourceFiles, &Row_File{
+				RepoPath: bestRepoPath(n.path, file.repoPaths),
+				Digest:   digest,
+			})
+		}
+		sort.Slice(row.SourceFiles, func(i, j int) bool {
+			return row.SourceFiles[i].RepoPath < row.SourceFiles[j].RepoPath
+		})
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].ImportPath < rows[j].ImportPath })
+	for _, row := range rows {
+		if err := f(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Removed calls f with the import path of each package marked dep:is-removed
+// in the quads added to d, in lexicographic order. If f reports an error,
+// Removed stops and returns that error.
+func (d *QuadDecoder) Removed(f func(pkg string) error) error {
+	var pkgs []string
+	for _, n := range d.nodes {
+		if n.isPackage && n.removed && n.path != "" {
+			pkgs = append(pkgs, n.path)
+		}
+	}
+	sort.Strings(pkgs)
+	for _, pkg := range pkgs {
+		if err := f(pkg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bestRepoPath returns the element of paths whose directory is the longest
+// suffix of the import path pkg, preferring the first on a tie.
+func bestRepoPath(pkg string, paths []string) string {
+	best, bestLen := paths[0], -1
+	for _, p := range paths {
+		dir := path.Dir(p)
+		if dir == "." {
+			dir = ""
+		}
+		if (dir == "" || pkg == dir || strings.HasSuffix(pkg, "/"+dir)) && len(dir) > bestLen {
+			best, bestLen = p, len(dir)
+		}
+	}
+	return best
+}
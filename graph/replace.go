@@ -0,0 +1,197 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	cgraph "github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/quad"
+)
+
+// ReplaceQuads returns a transaction that updates qs, a quad store holding an
+// encoding of the graph with stable IRIs, to reflect the changes described by
+// quads, an encoding of the graph with the Since or Packages option.
+//
+// Every quad whose subject is a package described by quads is replaced.
+// Removed packages are deleted, or marked missing if other packages still
+// import them. Files, repositories, and missing packages that are no longer
+// referred to are deleted. Thus if qs holds a complete encoding, the result
+// is the same as a complete encoding of the current graph, apart from changes
+// the options do not report (see QuadOptions).
+func ReplaceQuads(ctx context.Context, qs cgraph.QuadStore, quads []quad.Quad) (*cgraph.Transaction, error) {
+	isNew := make(map[quad.Quad]bool)
+	var pkgs []quad.Value                // packages described by quads, in order
+	changed := make(map[quad.Value]bool) // :: package → described
+	removed := make(map[quad.Value]bool) // :: package → marked removed
+	pathOf := make(map[quad.Value]quad.Value)
+	var uniq []quad.Quad
+	for _, q := range quads {
+		if isNew[q] {
+			continue
+		}
+		isNew[q] = true
+		uniq = append(uniq, q)
+		switch q.Predicate {
+		case relType:
+			if q.Object == typePackage && !changed[q.Subject] {
+				changed[q.Subject] = true
+				pkgs = append(pkgs, q.Subject)
+			}
+		case relRemoved:
+			removed[q.Subject] = q.Object == quad.Bool(true)
+		case relImportPath:
+			pathOf[q.Subject] = q.Object
+		}
+	}
+
+	// Removed packages are not marked in the store.
+	keep := make(map[quad.Quad]bool)
+	refs := make(map[quad.Value]bool) // nodes referred to by the new quads
+	var add []quad.Quad
+	for _, q := range uniq {
+		if !removed[q.Subject] {
+			keep[q] = true
+			add = append(add, q)
+			if isLink(q.Predicate) {
+				refs[q.Object] = true
+			}
+		}
+	}
+
+	// Delete the old quads of each package, and note the nodes they referred
+	// to, which may no longer be needed.
+	var del []quad.Quad
+	var cands []quad.Value
+	isCand := make(map[quad.Value]bool)
+	candidate := func(v quad.Value) {
+		if !isCand[v] {
+			isCand[v] = true
+			cands = append(cands, v)
+		}
+	}
+	for _, pkg := range pkgs {
+		old, err := subjectQuads(ctx, qs, pkg)
+		if err != nil {
+			return nil, err
+		}
+		for _, q := range old {
+			if keep[q] {
+				continue
+			}
+			del = append(del, q)
+			if isLink(q.Predicate) {
+				candidate(q.Object)
+			}
+		}
+		if removed[pkg] {
+			candidate(pkg)
+		}
+	}
+
+	for _, v := range cands {
+		if changed[v] && !removed[v] {
+			continue // described by the new quads
+		}
+		used := refs[v]
+		if !used {
+			ok, err := hasReferrer(ctx, qs, v, changed)
+			if err != nil {
+				return nil, err
+			}
+			used = ok
+		}
+		if removed[v] {
+			if used {
+				add = append(add,
+					quad.Quad{Subject: v, Predicate: relType, Object: typePackage},
+					quad.Quad{Subject: v, Predicate: relImportPath, Object: pathOf[v]},
+					quad.Quad{Subject: v, Predicate: relMissing, Object: quad.Bool(true)},
+				)
+			}
+			continue // its old quads were deleted above
+		} else if used {
+			continue
+		}
+
+		// Delete a file, repository, or missing package that is no longer
+		// referred to, but not a package that has a row.
+		old, err := subjectQuads(ctx, qs, v)
+		if err != nil {
+			return nil, err
+		}
+		var isPkg, isMissing bool
+		for _, q := range old {
+			isPkg = isPkg || (q.Predicate == relType && q.Object == typePackage)
+			isMissing = isMissing || (q.Predicate == relMissing && q.Object == quad.Bool(true))
+		}
+		if !isPkg || isMissing {
+			del = append(del, old...)
+		}
+	}
+
+	tx := cgraph.NewTransactionN(len(del) + len(add))
+	for _, q := range del {
+		tx.RemoveQuad(q)
+	}
+	for _, q := range add {
+		tx.AddQuad(q)
+	}
+	return tx, nil
+}
+
+// isLink reports whether pred relates one node of the graph to another.
+func isLink(pred quad.Value) bool {
+	return pred == relDefinedIn || pred == relHasFile || pred == relImports
+}
+
+// subjectQuads returns the quads of qs whose subject is v.
+func subjectQuads(ctx context.Context, qs cgraph.QuadStore, v quad.Value) ([]quad.Quad, error) {
+	ref := qs.ValueOf(v)
+	if ref == nil {
+		return nil, nil
+	}
+	var out []quad.Quad
+	it := qs.QuadIterator(quad.Subject, ref)
+	defer it.Close()
+	for it.Next(ctx) {
+		out = append(out, qs.Quad(it.Result()))
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("reading quads of %v: %v", v, err)
+	}
+	return out, nil
+}
+
+// hasReferrer reports whether qs has a link to v from a node other than the
+// packages in skip.
+func hasReferrer(ctx context.Context, qs cgraph.QuadStore, v quad.Value, skip map[quad.Value]bool) (bool, error) {
+	ref := qs.ValueOf(v)
+	if ref == nil {
+		return false, nil
+	}
+	it := qs.QuadIterator(quad.Object, ref)
+	defer it.Close()
+	for it.Next(ctx) {
+		if q := qs.Quad(it.Result()); isLink(q.Predicate) && !skip[q.Subject] {
+			return true, nil
+		}
+	}
+	if err := it.Err(); err != nil {
+		return false, fmt.Errorf("reading links to %v: %v", v, err)
+	}
+	return false, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"bitbucket.org/creachadair/stringset"
+	"github.com/creachadair/repodeps/storage"
+)
+
+// The search index maps words drawn from the name and import path of each
+// row to the import path of the row. Each entry is stored under the key
+//
+//	auxKey(searchKind, <word> NUL <import-path>)
+//
+// with an empty value, so that the rows containing words with a given prefix
+// can be found by a scan.
+
+func searchKey(word, pkg string) string { return auxKey(searchKind, word+"\x00"+pkg) }
+
+// SearchWords returns the words under which row is indexed for search: the
+// package name and each element of its import path, along with the parts of
+// each element separated by punctuation, all in lower case.
+func SearchWords(row *Row) []string {
+	if row == nil {
+		return nil
+	}
+	words := stringset.New()
+	if row.Name != "" {
+		words.Add(strings.ToLower(row.Name))
+	}
+	for _, elt := range strings.Split(strings.ToLower(row.ImportPath), "/") {
+		if elt == "" {
+			continue
+		}
+		words.Add(elt)
+		words.Add(strings.FieldsFunc(elt, isWordBreak)...)
+	}
+	return words.Elements()
+}
+
+// QueryWords splits a search query into words at whitespace and punctuation,
+// as SearchWords splits the elements of an import path, in lower case. Each
+// word of the query thus matches a prefix of a single indexed word.
+func QueryWords(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), isWordBreak)
+}
+
+func isWordBreak(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }
+
+// Search calls f with each import path indexed under a word having the given
+// prefix, together with the word. Words are visited in lexicographic order,
+// so the entries for a word equal to prefix are visited first; a package may
+// be visited once for each of its words. The prefix is matched without regard
+// to case. Index entries for rows that no longer exist may be reported if the
+// index was not maintained for all updates; see Reindex.
+//
+// If f reports an error, scanning terminates. If the error is
+// storage.ErrStopScan, Search returns nil. Otherwise Search returns the error
+// from f.
+func (g *Graph) Search(ctx context.Context, prefix string, f func(word, pkg string) error) error {
+	base := g.key(auxKey(searchKind, ""))
+	pfx := base + strings.ToLower(prefix)
+	return g.st.Scan(ctx, pfx, func(key string) error {
+		if !strings.HasPrefix(key, pfx) {
+			return storage.ErrStopScan
+		}
+		parts := strings.SplitN(strings.TrimPrefix(key, base), "\x00", 2)
+		if len(parts) != 2 {
+			return nil // skip malformed keys
+		}
+		return f(parts[0], parts[1])
+	})
+}
@@ -25,15 +25,19 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	pathpkg "path"
 	"path/filepath"
 	"strings"
 
+	"bitbucket.org/creachadair/stringset"
 	"github.com/creachadair/repodeps/deps"
 	"github.com/creachadair/repodeps/poll"
 )
 
-// Load reads the repository structure of a local directory. This will return
-// only a single repository, since local repositories are not rooted.
+// Load reads the repository structure of a local directory. This returns the
+// repository for dir, followed by a separate repository for each git submodule
+// whose contents are checked out inside it. Packages inside a submodule are
+// attributed to the submodule's repository rather than to dir.
 func Load(ctx context.Context, dir string, opts *deps.Options) ([]*deps.Repo, error) {
 	if opts == nil {
 		opts = new(deps.Options)
@@ -45,16 +49,103 @@ func Load(ctx context.Context, dir string, opts *deps.Options) ([]*deps.Repo, er
 	} else if len(remotes) == 0 {
 		return nil, errors.New("no remotes defined")
 	}
+	mod, _ := deps.ModuleName(dir)
+	primary := selectRemote(remotes, opts.RemotePolicy, mod)
+	if primary == nil {
+		return nil, errors.New("no remotes match the selection policy")
+	}
+	repoPrefix := opts.PackagePrefix
+	if repoPrefix == "" {
+		repoPrefix = poll.CleanRepoURL(primary.Url)
+	}
+	return loadRepo(ctx, dir, remotes, primary.Url, repoPrefix, opts)
+}
+
+// selectRemote returns the remote selected by p, or nil if no remote is
+// eligible under the policy. The module name is used if p.MatchModule is set.
+func selectRemote(remotes []*deps.Remote, p *deps.RemotePolicy, module string) *deps.Remote {
+	if p == nil {
+		p = new(deps.RemotePolicy)
+	}
+	filter := func(rs []*deps.Remote, keep func(*deps.Remote) bool) []*deps.Remote {
+		var out []*deps.Remote
+		for _, r := range rs {
+			if keep(r) {
+				out = append(out, r)
+			}
+		}
+		return out
+	}
+
+	cands := remotes
+	if len(p.Hosts) != 0 {
+		hosts := stringset.New(p.Hosts...)
+		cands = filter(cands, func(r *deps.Remote) bool {
+			host, _ := deps.HasDomain(poll.CleanRepoURL(r.Url))
+			return hosts.Contains(host)
+		})
+	}
+	if p.MatchModule && module != "" {
+		// If any candidate matches the module path, consider only those.
+		if ms := filter(cands, func(r *deps.Remote) bool {
+			base := poll.CleanRepoURL(r.Url)
+			return module == base || strings.HasPrefix(module, base+"/")
+		}); len(ms) != 0 {
+			cands = ms
+		}
+	}
+	for _, name := range p.Names {
+		for _, r := range cands {
+			if r.Name == name {
+				return r
+			}
+		}
+	}
+	if len(cands) == 0 {
+		return nil
+	}
+	return cands[0]
+}
+
+// loadRepo loads the packages of the repository at dir, attributing them to
+// the specified import prefix, along with those of its submodules.
+func loadRepo(ctx context.Context, dir string, remotes []*deps.Remote, url, repoPrefix string, opts *deps.Options) ([]*deps.Repo, error) {
+	subs, err := gitSubmodules(ctx, dir, url)
+	if err != nil {
+		return nil, fmt.Errorf("listing submodules: %v", err)
+	}
+	repo := &deps.Repo{
+		From:       dir,
+		Remotes:    remotes,
+		Submodules: subs,
+		PrimaryUrl: url,
+		Digest:     gitHead(ctx, dir),
+	}
+	repos := []*deps.Repo{repo}
+
+	// Load each submodule that is checked out as a separate repository.  Even
+	// if we do not load them, do not attribute their packages to this one.
+	isSub := make(map[string]bool)
+	for _, sub := range subs {
+		path := filepath.Join(dir, sub.Path)
+		isSub[path] = true
+		if opts.SkipSubmodules || !isPopulated(path) {
+			continue
+		}
+		srs, err := loadRepo(ctx, path, []*deps.Remote{{
+			Name: "origin",
+			Url:  sub.Url,
+		}}, sub.Url, poll.CleanRepoURL(sub.Url), opts)
+		if err != nil {
+			return nil, fmt.Errorf("submodule %q: %v", sub.Path, err)
+		}
+		repos = append(repos, srs...)
+	}
 
 	// The local path of the checkout may be inside a GOPATH, in which case we
 	// will wind up with the wrong import path. To avoid this, set up a virtual
 	// GOPATH containing only this package, in a directory named by the remote
 	// URL of the repository.
-	repo := &deps.Repo{From: dir, Remotes: remotes}
-	repoPrefix := opts.PackagePrefix
-	if repoPrefix == "" {
-		repoPrefix = poll.CleanRepoURL(remotes[0].Url)
-	}
 	vfs := newVFS(dir, repoPrefix)
 
 	var importMode build.ImportMode
@@ -65,15 +156,24 @@ func Load(ctx context.Context, dir string, opts *deps.Options) ([]*deps.Repo, er
 	// Find the import paths of the packages defined by this repository, and the
 	// import paths of their dependencies. This is basically "go list".
 	cmap := make(deps.PathLabelMap)
+	mods := make(deps.PathLabelMap) // :: directory → module path
 	err = filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		} else if !fi.IsDir() {
 			return nil // nothing to do here
+		} else if isSub[path] {
+			return filepath.SkipDir // attributed to the submodule
 		} else if deps.IsNonPackage(path) {
+			if filepath.Base(path) == "vendor" {
+				repo.Vendored = append(repo.Vendored, vendoredModules(path)...)
+			}
 			return filepath.SkipDir
-		} else if mod, ok := deps.ModuleName(path); ok && !deps.IsLocalPackage(mod) {
-			cmap.Add(path, mod) // module or submodule
+		} else if mod, ok := deps.ModuleName(path); ok {
+			mods.Add(path, mod)
+			if !deps.IsLocalPackage(mod) {
+				cmap.Add(path, mod) // module or submodule
+			}
 		}
 		select {
 		case <-ctx.Done():
@@ -93,6 +193,9 @@ func Load(ctx context.Context, dir string, opts *deps.Options) ([]*deps.Repo, er
 			Imports:    pkg.Imports,
 			Type:       deps.PackageType(pkg),
 		}
+		if mod, ok := mods.Enclosing(path); ok {
+			rec.Module = mod
+		}
 		if opts.UseImportComments {
 			if _, ok := deps.HasDomain(pkg.ImportComment); ok {
 				rec.ImportPath = pkg.ImportComment
@@ -121,7 +224,88 @@ func Load(ctx context.Context, dir string, opts *deps.Options) ([]*deps.Repo, er
 		repo.Packages = append(repo.Packages, rec)
 		return nil
 	})
-	return []*deps.Repo{repo}, err
+	return repos, err
+}
+
+// vendoredModules returns the modules listed by the modules.txt file in the
+// specified vendor directory, or nil if there is no such file.
+func vendoredModules(dir string) []*deps.Module {
+	data, err := os.ReadFile(filepath.Join(dir, "modules.txt"))
+	if err != nil {
+		return nil
+	}
+	return deps.ParseVendorModules(data)
+}
+
+// gitSubmodules returns the submodules declared by the .gitmodules file in
+// dir, if any. Relative submodule URLs are resolved against base, the URL of
+// the enclosing repository.
+func gitSubmodules(ctx context.Context, dir, base string) ([]*deps.Submodule, error) {
+	if _, err := os.Stat(filepath.Join(dir, ".gitmodules")); os.IsNotExist(err) {
+		return nil, nil // no submodules
+	}
+	cmd := exec.CommandContext(ctx, "git", "config", "--file", ".gitmodules",
+		"--get-regexp", `^submodule\..*\.(path|url)$`)
+	cmd.Dir = dir
+	bits, err := cmd.Output()
+	if err != nil {
+		if e, ok := err.(*exec.ExitError); ok && e.ExitCode() == 1 {
+			return nil, nil // no matching keys
+		}
+		return nil, err
+	}
+
+	// Each line has the form "submodule.<name>.<key> <value>".
+	byName := make(map[string]*deps.Submodule)
+	var subs []*deps.Submodule
+	for _, line := range strings.Split(strings.TrimSpace(string(bits)), "\n") {
+		kv := strings.SplitN(line, " ", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		i := strings.LastIndex(kv[0], ".")
+		name, key := strings.TrimPrefix(kv[0][:i], "submodule."), kv[0][i+1:]
+		sub, ok := byName[name]
+		if !ok {
+			sub = new(deps.Submodule)
+			byName[name] = sub
+			subs = append(subs, sub)
+		}
+		if key == "path" {
+			sub.Path = filepath.Clean(kv[1])
+		} else if strings.HasPrefix(kv[1], "./") || strings.HasPrefix(kv[1], "../") {
+			sub.Url = poll.FixRepoURL(pathpkg.Join(poll.CleanRepoURL(base), kv[1]))
+		} else {
+			sub.Url = poll.FixRepoURL(kv[1])
+		}
+	}
+
+	// Discard incomplete entries.
+	var out []*deps.Submodule
+	for _, sub := range subs {
+		if sub.Path != "" && sub.Url != "" {
+			out = append(out, sub)
+		}
+	}
+	return out, nil
+}
+
+// gitHead returns the commit digest (hex) of HEAD in dir, or "" if it cannot
+// be determined.
+func gitHead(ctx context.Context, dir string) string {
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--verify", "-q", "HEAD")
+	cmd.Dir = dir
+	bits, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(bits))
+}
+
+// isPopulated reports whether dir is a directory with at least one entry.
+func isPopulated(dir string) bool {
+	des, err := os.ReadDir(dir)
+	return err == nil && len(des) != 0
 }
 
 func gitRemotes(ctx context.Context, dir string) ([]*deps.Remote, error) {
@@ -0,0 +1,125 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/creachadair/repodeps/deps"
+)
+
+func TestSelectRemote(t *testing.T) {
+	remotes := []*deps.Remote{
+		{Name: "mine", Url: "https://github.com/me/repo"},
+		{Name: "origin", Url: "https://gitlab.com/team/repo"},
+		{Name: "upstream", Url: "https://github.com/team/repo"},
+	}
+	const module = "github.com/team/repo/v2"
+	tests := []struct {
+		policy *deps.RemotePolicy
+		want   string // remote name, or "" for none
+	}{
+		{nil, "mine"},
+		{&deps.RemotePolicy{}, "mine"},
+		{&deps.RemotePolicy{Names: []string{"upstream", "origin"}}, "upstream"},
+		{&deps.RemotePolicy{Names: []string{"nonesuch", "origin"}}, "origin"},
+		{&deps.RemotePolicy{Names: []string{"nonesuch"}}, "mine"},
+		{&deps.RemotePolicy{Hosts: []string{"gitlab.com"}}, "origin"},
+		{&deps.RemotePolicy{Hosts: []string{"github.com"}, Names: []string{"origin"}}, "mine"},
+		{&deps.RemotePolicy{Hosts: []string{"example.com"}}, ""},
+		{&deps.RemotePolicy{MatchModule: true}, "upstream"},
+		{&deps.RemotePolicy{MatchModule: true, Hosts: []string{"gitlab.com"}}, "origin"},
+	}
+	for _, test := range tests {
+		got := selectRemote(remotes, test.policy, module)
+		var name string
+		if got != nil {
+			name = got.Name
+		}
+		if name != test.want {
+			t.Errorf("selectRemote(%+v): got %q, want %q", test.policy, name, test.want)
+		}
+	}
+}
+
+func TestSubmodules(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(path, text string) {
+		t.Helper()
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatalf("Creating directory: %v", err)
+		} else if err := os.WriteFile(path, []byte(text), 0600); err != nil {
+			t.Fatalf("Writing file: %v", err)
+		}
+	}
+	writeFile(".gitmodules", `[submodule "lib"]
+	path = third_party/lib
+	url = ../lib.git
+[submodule "other"]
+	path = other
+	url = git@gitlab.com:team/other.git
+[submodule "broken"]
+	path = broken
+`)
+	writeFile("main.go", "package main\n")
+	writeFile("third_party/lib/lib.go", "package lib\n")
+	if err := os.Mkdir(filepath.Join(dir, "other"), 0700); err != nil {
+		t.Fatalf("Creating directory: %v", err)
+	}
+	ctx := context.Background()
+
+	const url = "https://github.com/x/main"
+	subs, err := gitSubmodules(ctx, dir, url)
+	if err != nil {
+		t.Fatalf("gitSubmodules failed: %v", err)
+	}
+	want := []*deps.Submodule{
+		{Path: "third_party/lib", Url: "https://github.com/x/lib"},
+		{Path: "other", Url: "https://gitlab.com/team/other"},
+	}
+	if len(subs) != len(want) {
+		t.Fatalf("gitSubmodules: got %d submodules, want %d", len(subs), len(want))
+	}
+	for i, w := range want {
+		if subs[i].Path != w.Path || subs[i].Url != w.Url {
+			t.Errorf("Submodule %d: got (%q, %q), want (%q, %q)",
+				i, subs[i].Path, subs[i].Url, w.Path, w.Url)
+		}
+	}
+
+	// The populated submodule is loaded as a separate repository, and its
+	// packages are not attributed to the enclosing one. The empty submodule
+	// is not loaded.
+	repos, err := loadRepo(ctx, dir, []*deps.Remote{{Name: "origin", Url: url}},
+		url, "github.com/x/main", new(deps.Options))
+	if err != nil {
+		t.Fatalf("loadRepo failed: %v", err)
+	}
+	wantRepos := []struct {
+		url  string
+		pkgs []string
+	}{
+		{url, []string{"github.com/x/main"}},
+		{"https://github.com/x/lib", []string{"github.com/x/lib"}},
+	}
+	if len(repos) != len(wantRepos) {
+		t.Fatalf("loadRepo: got %d repositories, want %d", len(repos), len(wantRepos))
+	}
+	for i, w := range wantRepos {
+		var pkgs []string
+		for _, pkg := range repos[i].Packages {
+			pkgs = append(pkgs, pkg.ImportPath)
+		}
+		if repos[i].PrimaryUrl != w.url {
+			t.Errorf("Repo %d URL: got %q, want %q", i, repos[i].PrimaryUrl, w.url)
+		}
+		if len(pkgs) != len(w.pkgs) || (len(pkgs) != 0 && pkgs[0] != w.pkgs[0]) {
+			t.Errorf("Repo %d packages: got %q, want %q", i, pkgs, w.pkgs)
+		}
+	}
+	if got := len(repos[0].Submodules); got != len(want) {
+		t.Errorf("Recorded submodules: got %d, want %d", got, len(want))
+	}
+}
@@ -32,10 +32,23 @@ import (
 //go:generate protoc --go_out=. poll.proto
 
 // A DB represents a cache of update statuses for repositories.
+//
+// Each status record is stored under the URL of its repository. Aliases and
+// bookkeeping records are stored under keys beginning with aliasPrefix and
+// metaPrefix respectively, both of which sort after any URL.
 type DB struct {
 	st storage.Interface
 }
 
+const (
+	reservedPrefix = "\xff" // sorts after any URL
+	aliasPrefix    = reservedPrefix + "alias\x00"
+	metaPrefix     = reservedPrefix + "meta\x00"
+
+	// The presence of this key records that MigrateKeys has been run.
+	migratedKey = metaPrefix + "canonical"
+)
+
 // NewDB constructs a database handle for the given storage.
 func NewDB(st storage.Interface) *DB { return &DB{st: st} }
 
@@ -52,8 +65,17 @@ func (db *DB) Status(ctx context.Context, url string) (*Status, error) {
 // Tags returns the status records for all tags of the specified URL.
 func (db *DB) Tags(ctx context.Context, base string) ([]*Status, error) {
 	var stat []*Status
-	if err := db.st.Scan(ctx, base, func(url string) error {
-		if url != base && !strings.HasPrefix(url, base+"/") {
+	if s, err := db.Status(ctx, base); err == nil {
+		stat = append(stat, s)
+	} else if err != storage.ErrKeyNotFound {
+		return nil, err
+	}
+
+	// Other URLs with base as a prefix may sort between base and its tags, so
+	// scan the tags separately.
+	tagPrefix := base + "@"
+	if err := db.st.Scan(ctx, tagPrefix, func(url string) error {
+		if !strings.HasPrefix(url, tagPrefix) {
 			return storage.ErrStopScan
 		}
 		s, err := db.Status(ctx, url)
@@ -72,16 +94,185 @@ func (db *DB) Tags(ctx context.Context, base string) ([]*Status, error) {
 
 // Remove removes the status record for the specified URL.
 func (db *DB) Remove(ctx context.Context, url, tag string) error {
+	return db.st.Delete(ctx, statusKey(url, tag))
+}
+
+// MigrateKeys moves status records stored under URLs that are not in
+// canonical form, as written by older versions of this package, to their
+// canonical keys. If a record already exists under the canonical key, the more
+// recently checked of the two is kept. If the legacy URL differs from the
+// canonical URL only in case, it is recorded as the package prefix of the
+// status unless a prefix is already set, so that package names are preserved.
+//
+// MigrateKeys records that it has run, and later calls do nothing. It returns
+// the number of records moved.
+func (db *DB) MigrateKeys(ctx context.Context) (int, error) {
+	var done timestamppb.Timestamp
+	if err := db.st.Load(ctx, migratedKey, &done); err == nil {
+		return 0, nil
+	} else if err != storage.ErrKeyNotFound {
+		return 0, err
+	}
+
+	// Collect the keys to migrate before modifying the database.
+	var legacy []string
+	if err := db.Scan(ctx, func(key string) error {
+		stat, err := db.Status(ctx, key)
+		if err != nil {
+			return nil // leave undecodable records for the caller to handle
+		}
+		if key != statusKey(CanonicalURL(stat.Repository), stat.Tag) {
+			legacy = append(legacy, key)
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	for _, key := range legacy {
+		stat, err := db.Status(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		old := stat.Repository
+		stat.Repository = CanonicalURL(old)
+		if stat.Prefix == "" {
+			stat.Prefix = CasePrefix(old, stat.Repository)
+		}
+		if cur, err := db.Status(ctx, statusKey(stat.Repository, stat.Tag)); err == nil {
+			if cur.LastCheck.AsTime().After(stat.LastCheck.AsTime()) {
+				stat = nil // the canonical record is newer; discard this one
+			}
+		} else if err != storage.ErrKeyNotFound {
+			return 0, err
+		}
+		if stat != nil {
+			if err := db.Put(ctx, stat); err != nil {
+				return 0, err
+			}
+		}
+		if err := db.st.Delete(ctx, key); err != nil {
+			return 0, err
+		}
+	}
+	return len(legacy), db.st.Store(ctx, migratedKey, timestamppb.Now())
+}
+
+// CasePrefix returns the package prefix spelled by url, if that differs from
+// the canonical URL canon only in case. Otherwise it returns "".  Canonical
+// URLs may fold case, but import paths are case-sensitive.
+func CasePrefix(url, canon string) string {
+	raw := CleanRepoURL(strings.TrimRight(url, "/"))
+	base := CleanRepoURL(canon)
+	if raw != base && strings.EqualFold(raw, base) {
+		return raw
+	}
+	return ""
+}
+
+func statusKey(url, tag string) string {
 	if tag != "" {
-		url += "@" + tag
+		return url + "@" + tag
 	}
-	return db.st.Delete(ctx, url)
+	return url
 }
 
 // Scan scans all the URLs in the database. If f reports an error, that error
 // is propagated to the caller.
 func (db *DB) Scan(ctx context.Context, f func(string) error) error {
-	return db.st.Scan(ctx, "", f)
+	return db.st.Scan(ctx, "", func(key string) error {
+		if strings.HasPrefix(key, reservedPrefix) {
+			return storage.ErrStopScan // no more statuses
+		}
+		return f(key)
+	})
+}
+
+// ScanStatus calls f with each status record in the database whose key is
+// lexicographically greater than or equal to start. If f reports an error,
+// scanning terminates. If the error is storage.ErrStopScan, ScanStatus returns
+// nil. Otherwise ScanStatus returns the error from f.
+func (db *DB) ScanStatus(ctx context.Context, start string, f func(*Status) error) error {
+	return db.st.Scan(ctx, start, func(key string) error {
+		if strings.HasPrefix(key, reservedPrefix) {
+			return storage.ErrStopScan // no more statuses
+		}
+		stat, err := db.Status(ctx, key)
+		if err != nil {
+			return err
+		}
+		return f(stat)
+	})
+}
+
+// Put stores stat under the key for its repository and tag, replacing any
+// existing status.
+func (db *DB) Put(ctx context.Context, stat *Status) error {
+	return db.st.Store(ctx, statusKey(stat.Repository, stat.Tag), stat)
+}
+
+// AddAlias records alias as an alternative URL for the repository at url.
+// Both URLs are stored in canonical form. It is an error if url is itself an
+// alias for alias.
+func (db *DB) AddAlias(ctx context.Context, alias, url string) error {
+	alias = CanonicalURL(alias)
+	target, err := db.Resolve(ctx, url)
+	if err != nil {
+		return err
+	} else if target == alias {
+		return fmt.Errorf("alias %q would refer to itself", alias)
+	}
+	return db.st.Store(ctx, aliasPrefix+alias, &Alias{
+		Alias:      alias,
+		Repository: target,
+	})
+}
+
+// PutAlias stores alias exactly as given, replacing any existing record for
+// the same alias. Unlike AddAlias, it does not canonicalize or resolve the
+// URLs, nor check for cycles; it is meant for restoring records read from
+// another database.
+func (db *DB) PutAlias(ctx context.Context, alias *Alias) error {
+	return db.st.Store(ctx, aliasPrefix+alias.Alias, alias)
+}
+
+// RemoveAlias removes the alias record for alias, if one exists.
+func (db *DB) RemoveAlias(ctx context.Context, alias string) error {
+	return db.st.Delete(ctx, aliasPrefix+CanonicalURL(alias))
+}
+
+// Resolve returns the canonical form of url, replaced by the repository it
+// refers to if it is a recorded alias.
+func (db *DB) Resolve(ctx context.Context, url string) (string, error) {
+	url = CanonicalURL(url)
+	seen := map[string]bool{url: true}
+	for {
+		var alias Alias
+		if err := db.st.Load(ctx, aliasPrefix+url, &alias); err == storage.ErrKeyNotFound {
+			return url, nil
+		} else if err != nil {
+			return "", err
+		} else if seen[alias.Repository] {
+			return "", fmt.Errorf("alias cycle at %q", alias.Repository)
+		}
+		url = alias.Repository
+		seen[url] = true
+	}
+}
+
+// Aliases calls f with each alias record in the database. If f reports an
+// error, that error is propagated to the caller.
+func (db *DB) Aliases(ctx context.Context, f func(*Alias) error) error {
+	return db.st.Scan(ctx, aliasPrefix, func(key string) error {
+		if !strings.HasPrefix(key, aliasPrefix) {
+			return storage.ErrStopScan // no more aliases
+		}
+		var alias Alias
+		if err := db.st.Load(ctx, key, &alias); err != nil {
+			return err
+		}
+		return f(&alias)
+	})
 }
 
 // CheckOptions control optional features of repostory checks.  A nil
@@ -294,6 +485,52 @@ func FixRepoURL(s string) string {
 	return "https://" + CleanRepoURL(s)
 }
 
+// CanonicalURL returns the canonical form of a repository URL, which is used
+// to identify the repository. In addition to the cleanup done by FixRepoURL,
+// it folds the case of the host name and discards a "www." prefix, trailing
+// slashes, and user names.  For well-known hosts it also discards links into
+// the repository contents (e.g., /tree/master/x), and for GitHub it folds the
+// case of the repository path, since GitHub treats it case-insensitively.
+//
+// Because import paths are case sensitive, the canonical form should not be
+// used to derive package names.
+func CanonicalURL(s string) string {
+	clean := strings.Trim(CleanRepoURL(strings.TrimRight(strings.TrimSpace(s), "/")), "/")
+	host, path := clean, ""
+	if i := strings.Index(clean, "/"); i >= 0 {
+		host, path = clean[:i], clean[i+1:]
+	}
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:] // discard user@
+	}
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+
+	switch host {
+	case "github.com":
+		path = strings.ToLower(firstN(path, 2))
+	case "bitbucket.org":
+		path = firstN(path, 2)
+	case "gitlab.com":
+		if i := strings.Index(path, "/-/"); i >= 0 {
+			path = path[:i] // discard /-/tree/..., /-/blob/..., etc.
+		}
+	}
+	path = strings.TrimSuffix(strings.TrimRight(path, "/"), ".git")
+	if path == "" {
+		return "https://" + host
+	}
+	return "https://" + host + "/" + path
+}
+
+// firstN returns the prefix of path comprising at most n components.
+func firstN(path string, n int) string {
+	parts := strings.SplitN(path, "/", n+1)
+	if len(parts) > n {
+		parts = parts[:n]
+	}
+	return strings.Join(parts, "/")
+}
+
 // CleanRepoURL removes protocol and format tags from a repository URL.
 func CleanRepoURL(url string) string {
 	if parts := strings.SplitN(url, "://", 2); len(parts) == 2 {
@@ -128,6 +128,62 @@ func (x *Status) GetUpdates() []*Status_Update {
 	return nil
 }
 
+// An Alias records an alternative URL for a repository.
+type Alias struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Alias      string `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`           // the alternative URL (canonical form)
+	Repository string `protobuf:"bytes,2,opt,name=repository,proto3" json:"repository,omitempty"` // the URL of the repository (canonical form)
+}
+
+func (x *Alias) Reset() {
+	*x = Alias{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_poll_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Alias) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Alias) ProtoMessage() {}
+
+func (x *Alias) ProtoReflect() protoreflect.Message {
+	mi := &file_poll_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Alias.ProtoReflect.Descriptor instead.
+func (*Alias) Descriptor() ([]byte, []int) {
+	return file_poll_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Alias) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+func (x *Alias) GetRepository() string {
+	if x != nil {
+		return x.Repository
+	}
+	return ""
+}
+
 type Status_Update struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -142,7 +198,7 @@ type Status_Update struct {
 func (x *Status_Update) Reset() {
 	*x = Status_Update{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_poll_proto_msgTypes[1]
+		mi := &file_poll_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -155,7 +211,7 @@ func (x *Status_Update) String() string {
 func (*Status_Update) ProtoMessage() {}
 
 func (x *Status_Update) ProtoReflect() protoreflect.Message {
-	mi := &file_poll_proto_msgTypes[1]
+	mi := &file_poll_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -213,8 +269,12 @@ var file_poll_proto_rawDesc = []byte{
 	0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
 	0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x04, 0x77, 0x68, 0x65, 0x6e,
 	0x12, 0x16, 0x0a, 0x06, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c,
-	0x52, 0x06, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x42, 0x08, 0x5a, 0x06, 0x2e, 0x3b, 0x70, 0x6f,
-	0x6c, 0x6c, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x52, 0x06, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x22, 0x3d, 0x0a, 0x05, 0x41, 0x6c, 0x69, 0x61,
+	0x73, 0x12, 0x14, 0x0a, 0x05, 0x61, 0x6c, 0x69, 0x61, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x61, 0x6c, 0x69, 0x61, 0x73, 0x12, 0x1e, 0x0a, 0x0a, 0x72, 0x65, 0x70, 0x6f, 0x73,
+	0x69, 0x74, 0x6f, 0x72, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x72, 0x65, 0x70,
+	0x6f, 0x73, 0x69, 0x74, 0x6f, 0x72, 0x79, 0x42, 0x08, 0x5a, 0x06, 0x2e, 0x3b, 0x70, 0x6f, 0x6c,
+	0x6c, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -229,16 +289,17 @@ func file_poll_proto_rawDescGZIP() []byte {
 	return file_poll_proto_rawDescData
 }
 
-var file_poll_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_poll_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
 var file_poll_proto_goTypes = []interface{}{
 	(*Status)(nil),                // 0: poll.Status
-	(*Status_Update)(nil),         // 1: poll.Status.Update
-	(*timestamppb.Timestamp)(nil), // 2: google.protobuf.Timestamp
+	(*Alias)(nil),                 // 1: poll.Alias
+	(*Status_Update)(nil),         // 2: poll.Status.Update
+	(*timestamppb.Timestamp)(nil), // 3: google.protobuf.Timestamp
 }
 var file_poll_proto_depIdxs = []int32{
-	2, // 0: poll.Status.last_check:type_name -> google.protobuf.Timestamp
-	1, // 1: poll.Status.updates:type_name -> poll.Status.Update
-	2, // 2: poll.Status.Update.when:type_name -> google.protobuf.Timestamp
+	3, // 0: poll.Status.last_check:type_name -> google.protobuf.Timestamp
+	2, // 1: poll.Status.updates:type_name -> poll.Status.Update
+	3, // 2: poll.Status.Update.when:type_name -> google.protobuf.Timestamp
 	3, // [3:3] is the sub-list for method output_type
 	3, // [3:3] is the sub-list for method input_type
 	3, // [3:3] is the sub-list for extension type_name
@@ -265,6 +326,18 @@ func file_poll_proto_init() {
 			}
 		}
 		file_poll_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Alias); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_poll_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*Status_Update); i {
 			case 0:
 				return &v.state
@@ -283,7 +356,7 @@ func file_poll_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_poll_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   3,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -2,14 +2,17 @@ package poll_test
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/creachadair/ffs/blob/memstore"
 	"github.com/creachadair/repodeps/poll"
 	"github.com/creachadair/repodeps/storage"
 	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func TestCheck(t *testing.T) {
@@ -66,3 +69,209 @@ func TestCheck(t *testing.T) {
 	}
 	t.Logf("Status message:\n%s", prototext.Format(&stat))
 }
+
+func TestCanonicalURL(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"github.com/foo/bar", "https://github.com/foo/bar"},
+		{"https://github.com/Foo/Bar", "https://github.com/foo/bar"},
+		{"http://www.github.com/foo/bar.git", "https://github.com/foo/bar"},
+		{"git@github.com:foo/bar.git", "https://github.com/foo/bar"},
+		{"ssh://git@github.com/foo/bar", "https://github.com/foo/bar"},
+		{"https://github.com/foo/bar/", "https://github.com/foo/bar"},
+		{"https://github.com/foo/bar/tree/master/baz", "https://github.com/foo/bar"},
+		{"https://GitHub.com/foo/bar.git/", "https://github.com/foo/bar"},
+		{"https://bitbucket.org/Foo/Bar/src/master/", "https://bitbucket.org/Foo/Bar"},
+		{"https://gitlab.com/Foo/sub/Bar/-/tree/main", "https://gitlab.com/Foo/sub/Bar"},
+		{"https://go.googlesource.com/Tools/", "https://go.googlesource.com/Tools"},
+		{"https://user@Example.COM/x/y.git", "https://example.com/x/y"},
+	}
+	for _, test := range tests {
+		if got := poll.CanonicalURL(test.input); got != test.want {
+			t.Errorf("CanonicalURL(%q): got %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestAliases(t *testing.T) {
+	db := poll.NewDB(storage.NewBlob(memstore.New()))
+	ctx := context.Background()
+
+	mustResolve := func(url, want string) {
+		t.Helper()
+		got, err := db.Resolve(ctx, url)
+		if err != nil {
+			t.Errorf("Resolve(%q) failed: %v", url, err)
+		} else if got != want {
+			t.Errorf("Resolve(%q): got %q, want %q", url, got, want)
+		}
+	}
+	mustResolve("github.com/Old/Name", "https://github.com/old/name")
+
+	if err := db.AddAlias(ctx, "github.com/old/name", "github.com/new/name"); err != nil {
+		t.Fatalf("AddAlias failed: %v", err)
+	}
+	mustResolve("https://github.com/Old/Name.git", "https://github.com/new/name")
+	mustResolve("github.com/new/name", "https://github.com/new/name")
+
+	if err := db.AddAlias(ctx, "github.com/new/name", "github.com/old/name"); err == nil {
+		t.Error("AddAlias: created a cycle without error")
+	}
+
+	var n int
+	if err := db.Aliases(ctx, func(a *poll.Alias) error {
+		n++
+		t.Logf("Alias %q → %q", a.Alias, a.Repository)
+		return nil
+	}); err != nil {
+		t.Errorf("Aliases failed: %v", err)
+	} else if n != 1 {
+		t.Errorf("Aliases: got %d records, want 1", n)
+	}
+	if err := db.Scan(ctx, func(url string) error {
+		t.Errorf("Scan: unexpected key %q", url)
+		return nil
+	}); err != nil {
+		t.Errorf("Scan failed: %v", err)
+	}
+
+	if err := db.RemoveAlias(ctx, "github.com/old/name"); err != nil {
+		t.Errorf("RemoveAlias failed: %v", err)
+	}
+	mustResolve("github.com/old/name", "https://github.com/old/name")
+}
+
+func TestPut(t *testing.T) {
+	db := poll.NewDB(storage.NewBlob(memstore.New()))
+	ctx := context.Background()
+
+	for _, stat := range []*poll.Status{
+		{Repository: "https://github.com/b/c"},
+		{Repository: "https://github.com/a/b", Tag: "v1"},
+		{Repository: "https://github.com/a/b"},
+	} {
+		if err := db.Put(ctx, stat); err != nil {
+			t.Fatalf("Put %v failed: %v", stat, err)
+		}
+	}
+	if err := db.AddAlias(ctx, "github.com/x/y", "github.com/a/b"); err != nil {
+		t.Fatalf("AddAlias failed: %v", err)
+	}
+	if stat, err := db.Status(ctx, "https://github.com/a/b@v1"); err != nil {
+		t.Errorf("Status failed: %v", err)
+	} else if stat.Tag != "v1" {
+		t.Errorf("Status tag: got %q, want v1", stat.Tag)
+	}
+
+	var got []string
+	if err := db.ScanStatus(ctx, "https://github.com/a/b@", func(stat *poll.Status) error {
+		got = append(got, stat.Repository+"@"+stat.Tag)
+		return nil
+	}); err != nil {
+		t.Fatalf("ScanStatus failed: %v", err)
+	}
+	want := []string{"https://github.com/a/b@v1", "https://github.com/b/c@"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("ScanStatus: got %q, want %q", got, want)
+	}
+}
+
+func TestMigrateKeys(t *testing.T) {
+	db := poll.NewDB(storage.NewBlob(memstore.New()))
+	ctx := context.Background()
+
+	old := time.Now().Add(-time.Hour)
+	for _, stat := range []*poll.Status{
+		// A legacy key that differs from its canonical form in case.
+		{Repository: "https://github.com/Foo/Bar", RefName: "legacy"},
+		{Repository: "https://github.com/Foo/Bar", Tag: "v1", RefName: "legacy"},
+
+		// A legacy key that is older than its canonical replacement.
+		{Repository: "https://github.com/Old/Dup", RefName: "legacy", LastCheck: timestamppb.New(old)},
+		{Repository: "https://github.com/old/dup", RefName: "current", LastCheck: timestamppb.Now()},
+
+		// A key already in canonical form.
+		{Repository: "https://github.com/a/b", RefName: "current"},
+	} {
+		if err := db.Put(ctx, stat); err != nil {
+			t.Fatalf("Put %v failed: %v", stat, err)
+		}
+	}
+
+	if n, err := db.MigrateKeys(ctx); err != nil {
+		t.Fatalf("MigrateKeys failed: %v", err)
+	} else if n != 3 {
+		t.Errorf("MigrateKeys: got %d records moved, want 3", n)
+	}
+
+	var got []string
+	if err := db.ScanStatus(ctx, "", func(stat *poll.Status) error {
+		got = append(got, fmt.Sprintf("%s@%s %s %s", stat.Repository, stat.Tag, stat.RefName, stat.Prefix))
+		return nil
+	}); err != nil {
+		t.Fatalf("ScanStatus failed: %v", err)
+	}
+	want := []string{
+		"https://github.com/a/b@ current ",
+		"https://github.com/foo/bar@ legacy github.com/Foo/Bar",
+		"https://github.com/foo/bar@v1 legacy github.com/Foo/Bar",
+		"https://github.com/old/dup@ current ",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("After migration:\n got %q\nwant %q", got, want)
+	}
+	if tags, err := db.Tags(ctx, "https://github.com/foo/bar"); err != nil {
+		t.Errorf("Tags failed: %v", err)
+	} else if len(tags) != 2 {
+		t.Errorf("Tags: got %d statuses, want 2", len(tags))
+	}
+
+	// A second migration has nothing to do, even if legacy keys reappear.
+	if err := db.Put(ctx, &poll.Status{Repository: "https://github.com/X/Y"}); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if n, err := db.MigrateKeys(ctx); err != nil || n != 0 {
+		t.Errorf("MigrateKeys again: got (%d, %v), want (0, nil)", n, err)
+	}
+}
+
+func TestTags(t *testing.T) {
+	db := poll.NewDB(storage.NewBlob(memstore.New()))
+	ctx := context.Background()
+
+	// The sibling keys sort between the untagged key and its tags.
+	for _, stat := range []*poll.Status{
+		{Repository: "https://github.com/foo/bar"},
+		{Repository: "https://github.com/foo/bar", Tag: "v1"},
+		{Repository: "https://github.com/foo/bar", Tag: "v2"},
+		{Repository: "https://github.com/foo/bar-x"},
+		{Repository: "https://github.com/foo/bar.go", Tag: "v1"},
+	} {
+		if err := db.Put(ctx, stat); err != nil {
+			t.Fatalf("Put %v failed: %v", stat, err)
+		}
+	}
+
+	check := func(base string, want ...string) {
+		t.Helper()
+		stats, err := db.Tags(ctx, base)
+		if err != nil {
+			t.Fatalf("Tags(%q) failed: %v", base, err)
+		}
+		var got []string
+		for _, stat := range stats {
+			got = append(got, stat.Repository+"@"+stat.Tag)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Tags(%q): got %q, want %q", base, got, want)
+		}
+	}
+	check("https://github.com/foo/bar",
+		"https://github.com/foo/bar@", "https://github.com/foo/bar@v1", "https://github.com/foo/bar@v2")
+	check("https://github.com/foo/bar.go", "https://github.com/foo/bar.go@v1")
+
+	if _, err := db.Tags(ctx, "https://github.com/foo/nonesuch"); err != storage.ErrKeyNotFound {
+		t.Errorf("Tags(nonesuch): got %v, want %v", err, storage.ErrKeyNotFound)
+	}
+}
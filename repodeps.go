@@ -45,7 +45,11 @@ var (
 	doImportComm  = flag.Bool("import-comments", true, "Parse and use import comments")
 	doTrimRepo    = flag.Bool("trim-repo", false, "Trim the repository prefix from each import path")
 	doStandardLib = flag.Bool("stdlib", false, "Treat packages in the input as standard libraries")
+	doSkipSubs    = flag.Bool("skip-submodules", false, "Do not load packages from git submodules")
 	pkgPrefix     = flag.String("prefix", "", "Attribute package names to this prefix")
+	remoteNames   = flag.String("remote-names", "", "Prefer remotes with these names (comma-separated)")
+	remoteHosts   = flag.String("remote-hosts", "", "Consider only remotes on these hosts (comma-separated)")
+	matchModule   = flag.Bool("match-module", false, "Prefer remotes matching the root module path")
 	taskTimeout   = flag.Duration("timeout", 5*time.Minute, "Timeout on processing a single repository")
 	concurrency   = flag.Int("concurrency", 32, "Maximum concurrent workers")
 
@@ -72,6 +76,12 @@ arguments are processed.
 If -sourcehash is set, the repository-relative paths and content digests of the
 Go source file in each packge are also captured.
 
+If the repository has several remotes, the first is used to identify it unless
+-remote-names, -remote-hosts, or -match-module select another.
+
+Packages inside git submodules are attributed to the submodule repository.  If
+-skip-submodules is set, they are not loaded at all.
+
 Inputs are processed concurrently with up to -concurrency in parallel.
 
 Options:
@@ -92,6 +102,12 @@ func main() {
 		TrimRepoPrefix:    *doTrimRepo,
 		StandardLibrary:   *doStandardLib,
 		PackagePrefix:     *pkgPrefix,
+		SkipSubmodules:    *doSkipSubs,
+		RemotePolicy: &deps.RemotePolicy{
+			Hosts:       tools.SplitList(*remoteHosts),
+			MatchModule: *matchModule,
+			Names:       tools.SplitList(*remoteNames),
+		},
 	}
 	defer cancel()
 	var db *graph.Graph
@@ -100,7 +116,7 @@ func main() {
 		if err != nil {
 			log.Fatalf("Opening graph: %v", err)
 		}
-		db = graph.New(storage.NewBlob(s))
+		db = graph.New(storage.NewBlob(s), nil)
 		defer func() {
 			if err := s.Close(); err != nil {
 				log.Fatalf("Closing graph: %v", err)
@@ -0,0 +1,68 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/creachadair/jrpc2/code"
+	"github.com/creachadair/repodeps/graph"
+)
+
+// Aggregate collapses the package graph into a graph of repositories or
+// modules, with edges weighted by the number of package-level dependencies.
+func (u *Server) Aggregate(ctx context.Context, req *AggregateReq) (*AggregateRsp, error) {
+	by := req.By
+	if by == "" {
+		by = graph.ByRepository.String()
+	}
+	level, err := graph.ParseLevel(by)
+	if err != nil {
+		return nil, jrpc2.Errorf(code.InvalidParams, "%v", err)
+	}
+	nodes, err := u.graph.Tag(req.Tag).Aggregate(ctx, level)
+	if err != nil {
+		return nil, err
+	}
+	rsp := &AggregateRsp{By: level.String(), NumNodes: len(nodes)}
+	for _, n := range nodes {
+		rsp.NumEdges += len(n.Edges)
+	}
+	if !req.CountOnly {
+		rsp.Nodes = nodes
+	}
+	return rsp, nil
+}
+
+// AggregateReq is the request parameter to the Aggregate method.
+type AggregateReq struct {
+	// Group packages by "repository" (default) or "module".
+	By string `json:"by"`
+
+	// Aggregate the rows stored under this tag (default: the untagged rows).
+	Tag string `json:"tag"`
+
+	// Only count the number of nodes and edges; do not emit them.
+	CountOnly bool `json:"countOnly"`
+}
+
+// AggregateRsp is the response from a successful Aggregate query.
+type AggregateRsp struct {
+	By       string        `json:"by"`       // the aggregation level
+	NumNodes int           `json:"numNodes"` // the number of nodes
+	NumEdges int           `json:"numEdges"` // the number of weighted edges
+	Nodes    []*graph.Node `json:"nodes,omitempty"`
+}
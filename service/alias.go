@@ -0,0 +1,122 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"errors"
+
+	"bitbucket.org/creachadair/stringset"
+	"github.com/creachadair/jrpc2"
+	"github.com/creachadair/jrpc2/code"
+	"github.com/creachadair/repodeps/graph"
+	"github.com/creachadair/repodeps/poll"
+)
+
+// Alias adds or removes an alternative URL for a repository. Once an alias is
+// recorded, requests that name the alias are treated as naming the repository.
+func (u *Server) Alias(ctx context.Context, req *AliasReq) (*AliasRsp, error) {
+	if u.opts.ReadOnly {
+		return nil, errors.New("database is read-only")
+	} else if req.Alias == "" {
+		return nil, jrpc2.Errorf(code.InvalidParams, "missing alias URL")
+	} else if req.Remove {
+		if err := u.repoDB.RemoveAlias(ctx, req.Alias); err != nil {
+			return nil, err
+		}
+		return &AliasRsp{Alias: poll.CanonicalURL(req.Alias)}, nil
+	} else if req.Repository == "" {
+		return nil, jrpc2.Errorf(code.InvalidParams, "missing repository URL")
+	}
+	if err := u.repoDB.AddAlias(ctx, req.Alias, req.Repository); err != nil {
+		return nil, jrpc2.Errorf(code.InvalidParams, "adding alias: %v", err)
+	}
+	target, err := u.repoDB.Resolve(ctx, req.Alias)
+	if err != nil {
+		return nil, err
+	}
+	return &AliasRsp{Alias: poll.CanonicalURL(req.Alias), Repository: target}, nil
+}
+
+// AliasReq is the request parameter to the Alias method.
+type AliasReq struct {
+	Alias      string `json:"alias"`      // the alternative URL
+	Repository string `json:"repository"` // the repository URL (unless removing)
+	Remove     bool   `json:"remove"`     // remove the alias rather than adding it
+}
+
+// AliasRsp is the response from a successful Alias call.
+type AliasRsp struct {
+	Alias      string `json:"alias"`                // canonical alias URL
+	Repository string `json:"repository,omitempty"` // canonical repository URL
+}
+
+// Aliases lists the repository aliases recorded in the database.
+func (u *Server) Aliases(ctx context.Context) (*AliasesRsp, error) {
+	rsp := new(AliasesRsp)
+	err := u.repoDB.Aliases(ctx, func(alias *poll.Alias) error {
+		rsp.Aliases = append(rsp.Aliases, &AliasRsp{
+			Alias:      alias.Alias,
+			Repository: alias.Repository,
+		})
+		return nil
+	})
+	return rsp, err
+}
+
+// AliasesRsp is the response from a successful Aliases call.
+type AliasesRsp struct {
+	Aliases []*AliasRsp `json:"aliases,omitempty"`
+}
+
+// repoResolver returns a function that maps repository URLs to the canonical
+// URL of the repository they refer to, following aliases. Results are cached
+// for the lifetime of the function.
+func (u *Server) repoResolver(ctx context.Context) func(string) string {
+	memo := make(map[string]string)
+	return func(url string) string {
+		if r, ok := memo[url]; ok {
+			return r
+		}
+		r, err := u.repoDB.Resolve(ctx, url)
+		if err != nil {
+			r = poll.CanonicalURL(url) // fall back to the lexical form
+		}
+		memo[url] = r
+		return r
+	}
+}
+
+// repoRows calls f with each row of g whose repository resolves to one of the
+// given canonical repository URLs, in order by import path starting from
+// start. Rows are found by the repository index of g where possible.
+func (u *Server) repoRows(ctx context.Context, g *graph.Graph, repos stringset.Set, start string, f func(*graph.Row) error) error {
+	resolve := u.repoResolver(ctx)
+	urls := repos.Elements()
+	if err := u.repoDB.Aliases(ctx, func(alias *poll.Alias) error {
+		if repos.Contains(resolve(alias.Repository)) {
+			urls = append(urls, alias.Alias)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return g.ScanRepoRows(ctx, urls, start, func(row *graph.Row) error {
+		if !repos.Contains(resolve(row.Repository)) {
+			return nil
+		}
+		return f(row)
+	})
+}
@@ -0,0 +1,134 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"sort"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/creachadair/jrpc2/code"
+	"github.com/creachadair/repodeps/deps"
+	"github.com/creachadair/repodeps/graph"
+)
+
+// Closure computes the transitive closure of the forward or reverse
+// dependencies of one or more packages. Results are ordered by import path.
+//
+// Paging bounds the size of each response, but not the cost of computing it:
+// every page recomputes the complete closure before selecting the packages it
+// reports, so reading N pages costs N times as much as reading one. Callers
+// that need the whole of a large closure should set a negative Limit to get
+// it in one response, or use CountOnly if only the size is needed.
+func (u *Server) Closure(ctx context.Context, req *ClosureReq) (*ClosureRsp, error) {
+	if len(req.Package) == 0 {
+		return nil, jrpc2.Errorf(code.InvalidParams, "missing package")
+	} else if req.MaxDepth < 0 {
+		return nil, jrpc2.Errorf(code.InvalidParams, "invalid maximum depth %d", req.MaxDepth)
+	}
+	if req.Limit == 0 {
+		req.Limit = u.opts.DefaultPageSize
+	}
+	g := u.graph.Tag(req.Tag)
+	roots, err := u.selectPackages(ctx, g, req.Package, "")
+	if err != nil {
+		return nil, err
+	}
+	depth, err := g.Closure(ctx, roots, &graph.ClosureOptions{
+		Reverse:  req.Reverse,
+		MaxDepth: req.MaxDepth,
+		Skip: func(pkg string, row *graph.Row) bool {
+			if req.ExcludeStdlib && isStdlib(pkg, row) {
+				return true
+			} else if _, ok := deps.HasDomain(pkg); req.DomainOnly && !ok {
+				return true
+			}
+			return false
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rsp := &ClosureRsp{NumPackages: len(depth)}
+	if req.CountOnly {
+		return rsp, nil
+	}
+	pkgs := make([]string, 0, len(depth))
+	for pkg := range depth {
+		if pkg >= string(req.PageKey) {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	sort.Strings(pkgs)
+	if req.Limit > 0 && len(pkgs) > req.Limit {
+		rsp.NextPage = []byte(pkgs[req.Limit])
+		pkgs = pkgs[:req.Limit]
+	}
+	for _, pkg := range pkgs {
+		rsp.Packages = append(rsp.Packages, &ClosureDep{Package: pkg, Depth: depth[pkg]})
+	}
+	return rsp, nil
+}
+
+// ClosureReq is the request parameter to the Closure method.
+type ClosureReq struct {
+	// Compute the closure of these packages. If a package ends with "/...",
+	// every package with that prefix is included.
+	Package StringList `json:"package"`
+
+	// If true, compute the closure of reverse dependencies (importers) rather
+	// than forward dependencies.
+	Reverse bool `json:"reverse"`
+
+	// If positive, include only packages at most this many imports away.
+	MaxDepth int `json:"maxDepth"`
+
+	// Exclude standard library packages.
+	ExcludeStdlib bool `json:"excludeStdlib"`
+
+	// Exclude packages whose import paths do not begin with a domain.
+	DomainOnly bool `json:"domainOnly"`
+
+	// Search the rows stored under this tag (default: the untagged rows).
+	Tag string `json:"tag"`
+
+	// Only count the number of packages in the closure; do not emit them.
+	CountOnly bool `json:"countOnly"`
+
+	// Return at most this many packages (0 uses a reasonable default; a
+	// negative value returns them all). Each page recomputes the full closure,
+	// so this limits the size of the response but not the work done to
+	// produce it.
+	Limit int `json:"limit"`
+
+	// Resume reading from this page key.
+	PageKey []byte `json:"pageKey"`
+}
+
+// ClosureRsp is the response from a successful Closure query.
+type ClosureRsp struct {
+	// The total number of packages in the closure, including the roots.
+	NumPackages int `json:"numPackages"`
+
+	Packages []*ClosureDep `json:"packages,omitempty"`
+	NextPage []byte        `json:"nextPage,omitempty"`
+}
+
+// ClosureDep is a single package in the result of a Closure query.
+type ClosureDep struct {
+	Package string `json:"package"`
+	Depth   int    `json:"depth"` // number of imports from the nearest root
+}
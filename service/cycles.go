@@ -0,0 +1,73 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/creachadair/jrpc2/code"
+	"github.com/creachadair/repodeps/graph"
+)
+
+// Cycles reports the dependency cycles among repositories or modules, along
+// with the package-level dependencies that create them.
+func (u *Server) Cycles(ctx context.Context, req *CyclesReq) (*CyclesRsp, error) {
+	by := req.By
+	if by == "" {
+		by = graph.ByRepository.String()
+	}
+	level, err := graph.ParseLevel(by)
+	if err != nil {
+		return nil, jrpc2.Errorf(code.InvalidParams, "%v", err)
+	}
+	cycles, err := u.graph.Tag(req.Tag).Cycles(ctx, level)
+	if err != nil {
+		return nil, err
+	}
+	rsp := &CyclesRsp{By: level.String(), NumCycles: len(cycles)}
+	if req.CountOnly {
+		return rsp, nil
+	}
+	for _, c := range cycles {
+		if req.ExcludeEdges {
+			c.Edges = nil
+		}
+		rsp.Cycles = append(rsp.Cycles, c)
+	}
+	return rsp, nil
+}
+
+// CyclesReq is the request parameter to the Cycles method.
+type CyclesReq struct {
+	// Find cycles among "repository" (default) or "module" nodes.
+	By string `json:"by"`
+
+	// Search the rows stored under this tag (default: the untagged rows).
+	Tag string `json:"tag"`
+
+	// Only count the number of cycles; do not emit them.
+	CountOnly bool `json:"countOnly"`
+
+	// Omit the package-level edges from each cycle.
+	ExcludeEdges bool `json:"excludeEdges"`
+}
+
+// CyclesRsp is the response from a successful Cycles query.
+type CyclesRsp struct {
+	By        string         `json:"by"`        // the aggregation level
+	NumCycles int            `json:"numCycles"` // the number of cycles found
+	Cycles    []*graph.Cycle `json:"cycles,omitempty"`
+}
@@ -0,0 +1,158 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"bitbucket.org/creachadair/stringset"
+	"github.com/creachadair/jrpc2"
+	"github.com/creachadair/jrpc2/code"
+	"github.com/creachadair/repodeps/deps"
+	"github.com/creachadair/repodeps/graph"
+	"github.com/creachadair/repodeps/storage"
+)
+
+// Diff reports the packages and direct dependencies added and removed between
+// two states of the graph, according to the recorded history. Both states are
+// read from the history, so that rows written before history was enabled, or
+// whose history has been pruned, do not appear as differences.
+func (u *Server) Diff(ctx context.Context, req *DiffReq) (*DiffRsp, error) {
+	if !u.opts.KeepHistory {
+		return nil, jrpc2.Errorf(code.InvalidRequest, "history is not enabled")
+	} else if req.From.snapshot().IsZero() {
+		return nil, jrpc2.Errorf(code.InvalidParams, "missing starting snapshot")
+	}
+	var to SnapshotSpec
+	if req.To != nil {
+		to = *req.To
+	}
+	if to.snapshot().IsZero() {
+		now := time.Now()
+		to.AsOf = &now
+	}
+	match, start := compilePackage(req.Package)
+	keep := func(dst string) bool {
+		if req.DomainOnly {
+			if _, ok := deps.HasDomain(dst); !ok {
+				return false
+			}
+		}
+		return !req.ExternalOnly || !match(dst)
+	}
+	load := func(spec SnapshotSpec) (map[string]stringset.Set, error) {
+		m := make(map[string]stringset.Set)
+		err := u.graph.ScanAt(ctx, spec.snapshot(), start, func(row *graph.Row) error {
+			if !match(row.ImportPath) {
+				if !strings.HasPrefix(row.ImportPath, start) {
+					return storage.ErrStopScan // no more matches are possible
+				}
+				return nil
+			}
+			out := stringset.New()
+			for _, dep := range row.Directs {
+				if keep(dep) {
+					out.Add(dep)
+				}
+			}
+			m[row.ImportPath] = out
+			return nil
+		})
+		return m, err
+	}
+	old, err := load(req.From)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := load(to)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp := new(DiffRsp)
+	oldDeps, curDeps := stringset.New(), stringset.New()
+	for pkg, ds := range old {
+		oldDeps.Update(ds)
+		if _, ok := cur[pkg]; !ok {
+			rsp.PackagesRemoved = append(rsp.PackagesRemoved, pkg)
+		}
+		for dep := range ds.Diff(cur[pkg]) {
+			rsp.EdgesRemoved = append(rsp.EdgesRemoved, &ReverseDep{Target: dep, Source: pkg})
+		}
+	}
+	for pkg, ds := range cur {
+		curDeps.Update(ds)
+		if _, ok := old[pkg]; !ok {
+			rsp.PackagesAdded = append(rsp.PackagesAdded, pkg)
+		}
+		for dep := range ds.Diff(old[pkg]) {
+			rsp.EdgesAdded = append(rsp.EdgesAdded, &ReverseDep{Target: dep, Source: pkg})
+		}
+	}
+	rsp.DepsAdded = curDeps.Diff(oldDeps).Elements()
+	rsp.DepsRemoved = oldDeps.Diff(curDeps).Elements()
+	sort.Strings(rsp.PackagesAdded)
+	sort.Strings(rsp.PackagesRemoved)
+	sortDeps(rsp.EdgesAdded)
+	sortDeps(rsp.EdgesRemoved)
+	return rsp, nil
+}
+
+// sortDeps sorts ds in order by source, then target.
+func sortDeps(ds []*ReverseDep) {
+	sort.Slice(ds, func(i, j int) bool {
+		if ds[i].Source == ds[j].Source {
+			return ds[i].Target < ds[j].Target
+		}
+		return ds[i].Source < ds[j].Source
+	})
+}
+
+// DiffReq is the request parameter to the Diff method.
+type DiffReq struct {
+	// Compare only rows for this package. If package ends with "/...", any
+	// row with that prefix is compared.
+	Package string `json:"package"`
+
+	// The earlier state to compare (required).
+	From SnapshotSpec `json:"from"`
+
+	// The later state to compare; if nil, the most recent recorded state is
+	// used.
+	To *SnapshotSpec `json:"to,omitempty"`
+
+	// Report only dependencies on packages not matched by package.
+	ExternalOnly bool `json:"externalOnly"`
+
+	// Report only dependencies on packages whose import paths begin with a
+	// domain, excluding the standard library.
+	DomainOnly bool `json:"domainOnly"`
+}
+
+// DiffRsp is the response from a successful Diff query.
+type DiffRsp struct {
+	PackagesAdded   []string      `json:"packagesAdded,omitempty"`
+	PackagesRemoved []string      `json:"packagesRemoved,omitempty"`
+	EdgesAdded      []*ReverseDep `json:"edgesAdded,omitempty"`
+	EdgesRemoved    []*ReverseDep `json:"edgesRemoved,omitempty"`
+
+	// Dependencies imported by some package in the later state, but by no
+	// package in the earlier state, and vice versa.
+	DepsAdded   []string `json:"depsAdded,omitempty"`
+	DepsRemoved []string `json:"depsRemoved,omitempty"`
+}
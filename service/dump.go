@@ -0,0 +1,308 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"bitbucket.org/creachadair/stringset"
+	"github.com/creachadair/jrpc2"
+	"github.com/creachadair/jrpc2/code"
+	"github.com/creachadair/repodeps/graph"
+	"github.com/creachadair/repodeps/poll"
+	"github.com/creachadair/repodeps/storage"
+)
+
+// The format name and schema version of dump streams. The version must be
+// incremented whenever a change to the record types would prevent an older
+// reader from restoring a dump correctly.
+const (
+	DumpFormat  = "repodeps-dump"
+	DumpVersion = 1
+)
+
+// Dump reports the contents of the graph and repository databases as a
+// sequence of records that Restore can load into empty databases. The first
+// page begins with a header record. Statuses are reported first, then aliases,
+// then the rows and repository records of each tagged view of the graph.
+//
+// Row histories are not included, so a dump and restore discards them. The
+// header records this in its Omits field.
+func (u *Server) Dump(ctx context.Context, req *DumpReq) (*DumpRsp, error) {
+	section, tag, start := parseDumpKey(string(req.PageKey))
+	limit := req.Limit
+	if limit <= 0 {
+		limit = u.opts.DefaultPageSize
+	}
+
+	rsp := new(DumpRsp)
+	if len(req.PageKey) == 0 {
+		rsp.Records = append(rsp.Records, &DumpRecord{Header: &DumpHeader{
+			Format:  DumpFormat,
+			Version: DumpVersion,
+			Created: time.Now().UTC(),
+			Omits:   []string{"history"},
+		}})
+	}
+
+	// add adds rec to the response if there is room, or else records key as
+	// the start of the next page and stops the scan.
+	add := func(key string, rec *DumpRecord) error {
+		if len(rsp.Records) >= limit {
+			rsp.NextPage = []byte(key)
+			return storage.ErrStopScan
+		}
+		rsp.Records = append(rsp.Records, rec)
+		return nil
+	}
+
+	if section <= dumpStatus {
+		if err := u.repoDB.ScanStatus(ctx, start, func(stat *poll.Status) error {
+			key := stat.Repository
+			if stat.Tag != "" {
+				key += "@" + stat.Tag
+			}
+			return add(dumpKey(dumpStatus, "", key), &DumpRecord{Status: stat})
+		}); err != nil {
+			return nil, fmt.Errorf("scanning statuses: %v", err)
+		} else if rsp.NextPage != nil {
+			return rsp, nil
+		}
+		start = ""
+	}
+	if section <= dumpAlias {
+		if err := u.repoDB.Aliases(ctx, func(alias *poll.Alias) error {
+			if alias.Alias < start {
+				return nil
+			}
+			return add(dumpKey(dumpAlias, "", alias.Alias), &DumpRecord{Alias: alias})
+		}); err != nil {
+			return nil, fmt.Errorf("scanning aliases: %v", err)
+		} else if rsp.NextPage != nil {
+			return rsp, nil
+		}
+		start = ""
+	}
+
+	tags := []string{""}
+	if err := u.graph.Tags(ctx, func(t string) error {
+		tags = append(tags, t)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("listing tags: %v", err)
+	}
+	for _, t := range tags {
+		if section > dumpAlias && t < tag {
+			continue // this view was completed on an earlier page
+		}
+		g := u.graph.Tag(t)
+		if section <= dumpRow || t != tag {
+			if err := g.Scan(ctx, start, func(row *graph.Row) error {
+				return add(dumpKey(dumpRow, t, row.ImportPath), &DumpRecord{Tag: t, Row: row})
+			}); err != nil {
+				return nil, fmt.Errorf("scanning rows: %v", err)
+			} else if rsp.NextPage != nil {
+				return rsp, nil
+			}
+			start = ""
+		}
+		if err := g.ScanRepos(ctx, start, func(rec *graph.Repo) error {
+			return add(dumpKey(dumpRepo, t, rec.Repository), &DumpRecord{Tag: t, Repo: rec})
+		}); err != nil {
+			return nil, fmt.Errorf("scanning repositories: %v", err)
+		} else if rsp.NextPage != nil {
+			return rsp, nil
+		}
+		start = ""
+	}
+	return rsp, nil
+}
+
+// DumpReq is the request parameter to the Dump method.
+type DumpReq struct {
+	Limit   int    `json:"limit"`
+	PageKey []byte `json:"pageKey"`
+}
+
+// DumpRsp is the response from a successful Dump query.
+type DumpRsp struct {
+	Records  []*DumpRecord `json:"records,omitempty"`
+	NextPage []byte        `json:"nextPage,omitempty"`
+}
+
+// A DumpHeader describes the format of a dump stream.
+type DumpHeader struct {
+	Format  string    `json:"format"`
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+
+	// Parts of the databases not included in the stream ("history").
+	Omits []string `json:"omits,omitempty"`
+}
+
+// check reports an error if h does not describe a stream that this version of
+// the service can restore.
+func (h *DumpHeader) check() error {
+	if h.Format != DumpFormat {
+		return fmt.Errorf("unknown dump format %q", h.Format)
+	} else if h.Version < 1 || h.Version > DumpVersion {
+		return fmt.Errorf("unsupported dump version %d", h.Version)
+	}
+	return nil
+}
+
+// A DumpRecord is a single record of a dump stream. Exactly one of the
+// pointer fields is set. Tag identifies the view of the graph containing a
+// row or repository record.
+type DumpRecord struct {
+	Header *DumpHeader  `json:"header,omitempty"`
+	Status *poll.Status `json:"status,omitempty"`
+	Alias  *poll.Alias  `json:"alias,omitempty"`
+	Tag    string       `json:"tag,omitempty"`
+	Row    *graph.Row   `json:"row,omitempty"`
+	Repo   *graph.Repo  `json:"repo,omitempty"`
+}
+
+// The sections of a dump stream, in order.
+const (
+	dumpStatus = iota
+	dumpAlias
+	dumpRow
+	dumpRepo
+)
+
+// dumpKey encodes a page key for the record with the given key in the given
+// section and tag of a dump.
+func dumpKey(section int, tag, key string) string {
+	return fmt.Sprintf("%d\x00%s\x00%s", section, tag, key)
+}
+
+// parseDumpKey decodes a page key constructed by dumpKey. An empty or invalid
+// key denotes the beginning of the dump.
+func parseDumpKey(pageKey string) (section int, tag, key string) {
+	parts := strings.SplitN(pageKey, "\x00", 3)
+	if len(parts) != 3 || len(parts[0]) != 1 {
+		return dumpStatus, "", ""
+	}
+	return int(parts[0][0] - '0'), parts[1], parts[2]
+}
+
+// Restore loads records produced by Dump into the databases. Unless req.Append
+// is true, the records must begin with a header, and both databases must be
+// empty. A large dump may be restored by a sequence of calls, all but the
+// first of which set req.Append. Records are stored exactly as dumped.
+//
+// Restore does not undo the records it has stored if it fails, so a failed
+// restore leaves the databases partly restored. Since restoring a record
+// again replaces it with the same value, the restore may be retried from the
+// beginning of the dump with req.Append set. Alternatively, stop the server,
+// delete its databases, and start over.
+func (u *Server) Restore(ctx context.Context, req *RestoreReq) (*RestoreRsp, error) {
+	if u.opts.ReadOnly {
+		return nil, errors.New("database is read-only")
+	}
+	if !req.Append {
+		if len(req.Records) == 0 || req.Records[0].Header == nil {
+			return nil, jrpc2.Errorf(code.InvalidParams, "missing dump header")
+		} else if empty, err := u.isEmpty(ctx); err != nil {
+			return nil, err
+		} else if !empty {
+			return nil, jrpc2.Errorf(code.InvalidParams, "database is not empty")
+		}
+	}
+
+	rsp := new(RestoreRsp)
+	seen := stringset.New() // tags of views whose indexes were initialized
+	for i, rec := range req.Records {
+		var err error
+		switch {
+		case rec.Header != nil:
+			err = rec.Header.check()
+		case rec.Status != nil:
+			err = u.repoDB.Put(ctx, rec.Status)
+			rsp.NumStatuses++
+		case rec.Alias != nil:
+			err = u.repoDB.PutAlias(ctx, rec.Alias)
+			rsp.NumAliases++
+		case rec.Row != nil:
+			g := u.graph.Tag(rec.Tag)
+			if !seen.Contains(rec.Tag) {
+				seen.Add(rec.Tag)
+				if err := u.initIndexes(ctx, g); err != nil {
+					return nil, err
+				}
+			}
+			if req.Keep {
+				if _, err := g.Row(ctx, rec.Row.ImportPath); err == nil {
+					rsp.NumSkipped++
+					continue
+				}
+			}
+			err = g.PutRow(ctx, rec.Row)
+			rsp.NumRows++
+		case rec.Repo != nil:
+			err = u.graph.Tag(rec.Tag).PutRepo(ctx, rec.Repo)
+			rsp.NumRepos++
+		default:
+			err = errors.New("empty record")
+		}
+		if err != nil {
+			return nil, jrpc2.Errorf(code.InvalidParams, "record %d: %v", i+1, err)
+		}
+	}
+	return rsp, nil
+}
+
+// isEmpty reports whether both databases are empty.
+func (u *Server) isEmpty(ctx context.Context) (bool, error) {
+	empty := true
+	stop := func(string) error { empty = false; return storage.ErrStopScan }
+	if err := u.repoDB.Scan(ctx, stop); err != nil {
+		return false, err
+	} else if err := u.repoDB.Aliases(ctx, func(*poll.Alias) error {
+		return stop("")
+	}); err != nil {
+		return false, err
+	} else if err := u.graph.List(ctx, "", stop); err != nil {
+		return false, err
+	} else if err := u.graph.Tags(ctx, stop); err != nil {
+		return false, err
+	}
+	return empty, nil
+}
+
+// RestoreReq is the request parameter to the Restore method.
+type RestoreReq struct {
+	Records []*DumpRecord `json:"records"`
+
+	// Add to the existing contents of the databases, which need not be empty.
+	Append bool `json:"append"`
+
+	// Do not replace rows that already exist.
+	Keep bool `json:"keep"`
+}
+
+// RestoreRsp is the response from a successful Restore call.
+type RestoreRsp struct {
+	NumStatuses int `json:"numStatuses,omitempty"`
+	NumAliases  int `json:"numAliases,omitempty"`
+	NumRows     int `json:"numRows,omitempty"`
+	NumRepos    int `json:"numRepos,omitempty"`
+	NumSkipped  int `json:"numSkipped,omitempty"` // rows kept, with req.Keep
+}
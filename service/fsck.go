@@ -0,0 +1,201 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"bitbucket.org/creachadair/stringset"
+	"github.com/creachadair/jrpc2"
+	"github.com/creachadair/jrpc2/code"
+	"github.com/creachadair/repodeps/graph"
+)
+
+// The kinds of problem reported by Fsck, in addition to those reported by
+// the graph.Check method.
+const (
+	BadStatus   = "badStatus"   // a repository status that cannot be decoded
+	OrphanRow   = "orphanRow"   // a row whose repository has no status
+	EmptyStatus = "emptyStatus" // a repository status with no rows
+)
+
+// Fsck checks the consistency of the graph and repository databases, and
+// optionally repairs the problems it finds. Every tagged view of the graph is
+// checked.
+//
+// Rows whose repository has no status are expected for packages that were
+// added directly rather than by a repository update, such as the standard
+// library, so OrphanRow problems are repaired only if requested by name, and
+// not by "all".
+func (u *Server) Fsck(ctx context.Context, req *FsckReq) (*FsckRsp, error) {
+	repair := stringset.New(req.Repair...)
+	if repair.Contains("all") {
+		repair.Add(graph.BadRow, graph.KeyMismatch, graph.BadRepo,
+			graph.EmptyRepo, graph.BadVersion, BadStatus, EmptyStatus)
+	}
+	if u.opts.ReadOnly && !repair.Empty() {
+		return nil, errors.New("database is read-only")
+	} else if !u.tryScanning() {
+		return nil, jrpc2.Errorf(code.SystemError, "scan already in progress")
+	}
+	defer u.doneScanning()
+
+	maxExamples := req.MaxExamples
+	if maxExamples == 0 {
+		maxExamples = 10
+	}
+	rsp := &FsckRsp{Problems: make(map[string]*FsckClass)}
+	start := time.Now()
+	defer func() { rsp.Elapsed = time.Since(start) }()
+
+	report := func(tag string, p *graph.Problem) {
+		u.pushLog(ctx, req.LogProblems, "log.problem", &FsckProblem{Tag: tag, Problem: p})
+		cls := rsp.Problems[p.Kind]
+		if cls == nil {
+			cls = new(FsckClass)
+			rsp.Problems[p.Kind] = cls
+		}
+		cls.Count++
+		rsp.NumProblems++
+		if p.Repaired {
+			cls.Repaired++
+			rsp.NumRepaired++
+		}
+		if maxExamples > 0 && len(cls.Examples) < maxExamples {
+			cls.Examples = append(cls.Examples, &FsckProblem{Tag: tag, Problem: p})
+		}
+	}
+	fix := func(p *graph.Problem, f func() error) {
+		if !repair.Contains(p.Kind) {
+			return
+		} else if err := f(); err != nil {
+			p.Detail += "; repair failed: " + err.Error()
+		} else {
+			p.Repaired = true
+		}
+	}
+
+	// Check the repository statuses, and record which repositories have a
+	// status under each tag.
+	resolve := u.repoResolver(ctx)
+	status := make(map[string]map[string]string) // :: tag → repository → status URL
+	if err := u.repoDB.Scan(ctx, func(key string) error {
+		stat, err := u.repoDB.Status(ctx, key)
+		if err != nil {
+			p := &graph.Problem{Kind: BadStatus, Key: key, Detail: err.Error()}
+			fix(p, func() error { return u.repoDB.Remove(ctx, key, "") })
+			report("", p)
+			return nil
+		}
+		rs := status[stat.Tag]
+		if rs == nil {
+			rs = make(map[string]string)
+			status[stat.Tag] = rs
+		}
+		rs[resolve(stat.Repository)] = stat.Repository
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("scanning statuses: %v", err)
+	}
+
+	// Check each view of the graph, including tags that have statuses but no
+	// rows.
+	tags := stringset.New("")
+	for tag := range status {
+		tags.Add(tag)
+	}
+	if err := u.graph.Tags(ctx, func(tag string) error {
+		tags.Add(tag)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("listing tags: %v", err)
+	}
+	canRepair := func(kind string) bool { return repair.Contains(kind) }
+	for _, tag := range tags.Elements() {
+		g := u.graph.Tag(tag)
+		if err := g.Check(ctx, canRepair, func(p *graph.Problem) error {
+			report(tag, p)
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("checking graph: %v", err)
+		}
+
+		// Rows that could not be loaded were reported by the check.
+		repos := stringset.New()
+		if err := g.List(ctx, "", func(key string) error {
+			row, err := g.Row(ctx, key)
+			if err != nil {
+				return nil
+			}
+			repo := resolve(row.Repository)
+			repos.Add(repo)
+			if _, ok := status[tag][repo]; !ok {
+				p := &graph.Problem{Kind: OrphanRow, Key: key, Detail: row.Repository}
+				fix(p, func() error { return g.Remove(ctx, key) })
+				report(tag, p)
+			}
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("scanning rows: %v", err)
+		}
+		for repo, url := range status[tag] {
+			if !repos.Contains(repo) {
+				p := &graph.Problem{Kind: EmptyStatus, Key: url}
+				fix(p, func() error { return u.repoDB.Remove(ctx, url, tag) })
+				report(tag, p)
+			}
+		}
+	}
+	return rsp, nil
+}
+
+// FsckReq is the request parameter to the Fsck method.
+type FsckReq struct {
+	// Repair problems of these kinds; "all" repairs every kind except
+	// OrphanRow, which must be named explicitly.
+	Repair StringList `json:"repair"`
+
+	// Report at most this many examples of each kind of problem.
+	// Zero means 10; a negative value reports no examples.
+	MaxExamples int `json:"maxExamples"`
+
+	LogProblems bool `json:"logProblems"` // push problem notifications
+}
+
+// FsckRsp is the response from a successful Fsck query.
+type FsckRsp struct {
+	NumProblems int                   `json:"numProblems"`
+	NumRepaired int                   `json:"numRepaired"`
+	Problems    map[string]*FsckClass `json:"problems,omitempty"` // :: kind → summary
+
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// FsckClass summarizes the problems of a single kind.
+type FsckClass struct {
+	Count    int            `json:"count"`
+	Repaired int            `json:"repaired,omitempty"`
+	Examples []*FsckProblem `json:"examples,omitempty"`
+}
+
+// FsckProblem is a problem found in a tagged view of the graph. Problems with
+// repository statuses are reported under the tag of the status.
+type FsckProblem struct {
+	Tag string `json:"tag,omitempty"`
+	*graph.Problem
+}
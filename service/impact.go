@@ -0,0 +1,247 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/creachadair/jrpc2/code"
+	"github.com/creachadair/repodeps/graph"
+)
+
+// Impact computes, for each row of the graph, the number of packages that
+// transitively depend on it and the number of other repositories containing
+// those packages.
+func (u *Server) Impact(ctx context.Context, req *ImpactReq) (*ImpactRsp, error) {
+	if u.opts.ReadOnly && req.Update {
+		return nil, errors.New("database is read-only")
+	} else if !u.tryScanning() {
+		return nil, jrpc2.Errorf(code.SystemError, "scan already in progress")
+	}
+	defer u.doneScanning()
+
+	rsp := new(ImpactRsp)
+	start := time.Now()
+	defer func() { rsp.Elapsed = time.Since(start) }()
+
+	type progress struct {
+		N int           `json:"n,omitempty"`
+		P string        `json:"package,omitempty"`
+		D int           `json:"dependents,omitempty"`
+		R int           `json:"dependentRepos,omitempty"`
+		L string        `json:"msg"`
+		E time.Duration `json:"elapsed,omitempty"`
+	}
+
+	// Load the reverse dependency graph. Packages are numbered in scan order,
+	// and packages without rows are omitted since nothing is stored for them.
+	g := u.graph.Tag(req.Tag)
+	m := newImpactMap()
+	if err := g.Scan(ctx, "", func(row *graph.Row) error {
+		m.add(row)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("loading graph: %v", err)
+	}
+	m.link()
+	u.pushLog(ctx, req.LogProgress, "log.progress", progress{
+		N: len(m.pkgs), L: "graph loaded", E: time.Since(start),
+	})
+	rsp.NumRows = len(m.pkgs)
+
+	// Compute the counts for each package.
+	m.compute(func(n int) {
+		if n%10000 == 0 {
+			u.pushLog(ctx, req.LogProgress, "log.progress", progress{
+				N: n, L: "packages complete", E: time.Since(start),
+			})
+		}
+	})
+	u.pushLog(ctx, req.LogProgress, "log.progress", progress{
+		L: "analysis complete", E: time.Since(start),
+	})
+
+	err := g.ScanUpdate(ctx, "", func(row *graph.Row) bool {
+		id, ok := m.id[row.ImportPath]
+		if !ok {
+			return false
+		}
+		nd, nr := m.deps[id], m.repos[id]
+		if int64(nd) == row.Dependents && int64(nr) == row.DependentRepos {
+			return false
+		}
+		u.pushLog(ctx, req.LogUpdates, "log.updateImpact", progress{P: row.ImportPath, D: nd, R: nr})
+		rsp.NumUpdates++
+		if !req.Update {
+			return false
+		}
+		row.Dependents = int64(nd)
+		row.DependentRepos = int64(nr)
+		return true
+	})
+	return rsp, err
+}
+
+// ImpactReq is the request parameter to the Impact method.
+type ImpactReq struct {
+	// Analyze the rows stored under this tag (default: the untagged rows).
+	Tag string `json:"tag"`
+
+	// Write the updated counts back to the database.
+	Update bool `json:"update"`
+
+	LogProgress bool `json:"logProgress"` // push progress notifications
+	LogUpdates  bool `json:"logUpdates"`  // push update notifications
+}
+
+// ImpactRsp is the response from a successful Impact query.
+type ImpactRsp struct {
+	NumRows    int `json:"numRows"`    // total count of rows examined
+	NumUpdates int `json:"numUpdates"` // number of rows whose counts changed
+
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// An impactMap holds the reverse dependency graph for an impact analysis.
+type impactMap struct {
+	id     map[string]int // :: import path → package ID
+	repoID map[string]int // :: repository URL → repository ID
+	pkgs   []string       // :: package ID → import path
+	repo   []int          // :: package ID → repository ID
+	fwd    [][]string     // :: package ID → direct dependencies
+	rev    [][]int        // :: package ID → IDs of direct importers
+
+	deps, repos []int // :: package ID → transitive dependent counts
+}
+
+func newImpactMap() *impactMap {
+	return &impactMap{id: make(map[string]int), repoID: make(map[string]int)}
+}
+
+// add adds row to the map. The reverse edges are resolved by link.
+func (m *impactMap) add(row *graph.Row) {
+	rid, ok := m.repoID[row.Repository]
+	if !ok {
+		rid = len(m.repoID)
+		m.repoID[row.Repository] = rid
+	}
+	m.id[row.ImportPath] = len(m.pkgs)
+	m.pkgs = append(m.pkgs, row.ImportPath)
+	m.repo = append(m.repo, rid)
+	m.fwd = append(m.fwd, row.Directs)
+}
+
+// link constructs the reverse edges among the packages that were added.
+func (m *impactMap) link() {
+	m.rev = make([][]int, len(m.pkgs))
+	for src, directs := range m.fwd {
+		for _, dep := range directs {
+			if dst, ok := m.id[dep]; ok && dst != src {
+				m.rev[dst] = append(m.rev[dst], src)
+			}
+		}
+	}
+	m.fwd = nil // no longer needed
+}
+
+// compute counts the transitive dependents of each package, calling progress
+// after each package.
+//
+// Every package in a strongly-connected component of the reverse graph has
+// the same dependents (apart from itself), so the counts are computed by one
+// traversal of the condensed graph from each component. The cost of each
+// traversal is proportional to the number of components and edges it
+// reaches, so the total cost is proportional to the size of the transitive
+// closure of the condensed graph: linear for a sparse graph, but quadratic in
+// the worst case, when most components depend on most others.
+func (m *impactMap) compute(progress func(int)) {
+	n := len(m.pkgs)
+	m.deps, m.repos = make([]int, n), make([]int, n)
+
+	comps := graph.StrongComponents(n, func(v int) []int { return m.rev[v] })
+	compOf := make([]int, n)
+	for c, comp := range comps {
+		for _, v := range comp {
+			compOf[v] = c
+		}
+	}
+	succ := make([][]int, len(comps)) // :: component → importing components
+	for c, comp := range comps {
+		seen := map[int]bool{c: true}
+		for _, v := range comp {
+			for _, imp := range m.rev[v] {
+				if d := compOf[imp]; !seen[d] {
+					seen[d] = true
+					succ[c] = append(succ[c], d)
+				}
+			}
+		}
+	}
+
+	// Stamps record which components and repositories were seen in the
+	// current traversal, so the marks need not be cleared between traversals.
+	seen := make([]int, len(comps))
+	seenRepo := make([]int, len(m.repoID))
+	var queue []int
+	done := 0
+	for c, comp := range comps {
+		stamp := c + 1
+		seen[c] = stamp
+
+		// Count the packages and repositories of the components reachable
+		// from c, not including c itself.
+		var numDeps, numRepos int
+		queue = append(queue[:0], c)
+		for len(queue) != 0 {
+			cur := queue[0]
+			queue = queue[1:]
+			for _, d := range succ[cur] {
+				if seen[d] == stamp {
+					continue
+				}
+				seen[d] = stamp
+				numDeps += len(comps[d])
+				for _, v := range comps[d] {
+					if r := m.repo[v]; seenRepo[r] != stamp {
+						seenRepo[r] = stamp
+						numRepos++
+					}
+				}
+				queue = append(queue, d)
+			}
+		}
+
+		// Each package of c also depends on the other packages of c. Count the
+		// members of c by repository, for repositories not already reached.
+		inComp := make(map[int]int) // :: repository ID → packages of c
+		for _, v := range comp {
+			if r := m.repo[v]; seenRepo[r] != stamp {
+				inComp[r]++
+			}
+		}
+		for _, v := range comp {
+			// The package's own repository is either reached or in c, and is
+			// not counted.
+			m.deps[v] = numDeps + len(comp) - 1
+			m.repos[v] = numRepos + len(inComp) - 1
+			done++
+			progress(done)
+		}
+	}
+}
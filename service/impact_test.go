@@ -0,0 +1,58 @@
+package service
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/creachadair/repodeps/graph"
+)
+
+func TestImpactCompute(t *testing.T) {
+	// Build a random graph with cycles, and compare the counts to those found
+	// by a separate traversal from each package.
+	const numPkgs, numRepos, numEdges = 60, 8, 90
+	rng := rand.New(rand.NewSource(1))
+	rows := make([]*graph.Row, numPkgs)
+	for i := range rows {
+		rows[i] = &graph.Row{
+			ImportPath: fmt.Sprintf("p%d", i),
+			Repository: fmt.Sprintf("r%d", rng.Intn(numRepos)),
+		}
+	}
+	for i := 0; i < numEdges; i++ {
+		src, dst := rows[rng.Intn(numPkgs)], rows[rng.Intn(numPkgs)]
+		src.Directs = append(src.Directs, dst.ImportPath)
+	}
+
+	m := newImpactMap()
+	for _, row := range rows {
+		m.add(row)
+	}
+	m.link()
+	m.compute(func(int) {})
+
+	for id := 0; id < numPkgs; id++ {
+		seen := map[int]bool{id: true}
+		repos := make(map[int]bool)
+		queue := []int{id}
+		for len(queue) != 0 {
+			cur := queue[0]
+			queue = queue[1:]
+			for _, imp := range m.rev[cur] {
+				if !seen[imp] {
+					seen[imp] = true
+					repos[m.repo[imp]] = true
+					queue = append(queue, imp)
+				}
+			}
+		}
+		delete(repos, m.repo[id])
+		if got, want := m.deps[id], len(seen)-1; got != want {
+			t.Errorf("Package %s dependents: got %d, want %d", m.pkgs[id], got, want)
+		}
+		if got, want := m.repos[id], len(repos); got != want {
+			t.Errorf("Package %s dependent repos: got %d, want %d", m.pkgs[id], got, want)
+		}
+	}
+}
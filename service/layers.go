@@ -0,0 +1,119 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/creachadair/jrpc2/code"
+	"github.com/creachadair/repodeps/graph"
+	"github.com/creachadair/repodeps/storage"
+)
+
+// Layers computes a topological layering of the dependency graph induced by a
+// set of packages or repositories, so that each layer depends only on earlier
+// layers. Cycles that prevent a complete layering are reported.
+func (u *Server) Layers(ctx context.Context, req *LayersReq) (*LayersRsp, error) {
+	if len(req.Package) == 0 && len(req.Repository) == 0 {
+		return nil, jrpc2.Errorf(code.InvalidParams, "missing packages or repositories")
+	} else if len(req.Package) != 0 && len(req.Repository) != 0 {
+		return nil, jrpc2.Errorf(code.InvalidParams, "packages and repositories are mutually exclusive")
+	}
+	g := u.graph.Tag(req.Tag)
+	var adj map[string][]string
+	var err error
+	if len(req.Package) != 0 {
+		adj, err = u.packageLayers(ctx, g, req.Package)
+	} else {
+		adj, err = u.repoLayers(ctx, g, req.Repository)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &LayersRsp{NumNodes: len(adj), Layering: graph.Layer(adj)}, nil
+}
+
+// packageLayers returns the dependency graph induced by the selected packages.
+func (u *Server) packageLayers(ctx context.Context, g *graph.Graph, pkgs []string) (map[string][]string, error) {
+	sel, err := u.selectPackages(ctx, g, pkgs, "")
+	if err != nil {
+		return nil, err
+	}
+	adj := make(map[string][]string)
+	for _, pkg := range sel {
+		row, err := g.Row(ctx, pkg)
+		if err == storage.ErrKeyNotFound {
+			adj[pkg] = nil // no known dependencies
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		adj[pkg] = row.Directs
+	}
+	return adj, nil
+}
+
+// repoLayers returns the repository dependency graph induced by the selected
+// repositories: repository A depends on B if any package in A imports a
+// package in B.
+func (u *Server) repoLayers(ctx context.Context, g *graph.Graph, repos []string) (map[string][]string, error) {
+	resolve := u.repoResolver(ctx)
+	adj := make(map[string][]string)
+	for _, repo := range repos {
+		adj[resolve(repo)] = nil
+	}
+	repoOf := make(map[string]string) // :: import path → repository
+	directs := make(map[string][]string)
+	if err := g.Scan(ctx, "", func(row *graph.Row) error {
+		repo := resolve(row.Repository)
+		repoOf[row.ImportPath] = repo
+		if _, ok := adj[repo]; ok {
+			directs[row.ImportPath] = row.Directs
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	for pkg, ds := range directs {
+		src := repoOf[pkg]
+		for _, dep := range ds {
+			if dst, ok := repoOf[dep]; ok && dst != src {
+				adj[src] = append(adj[src], dst)
+			}
+		}
+	}
+	return adj, nil
+}
+
+// LayersReq is the request parameter to the Layers method.
+type LayersReq struct {
+	// Layer these packages. If a package ends with "/...", every package with
+	// that prefix is included.
+	Package StringList `json:"package"`
+
+	// Layer these repositories. Exclusive of package.
+	Repository StringList `json:"repository"`
+
+	// Use the rows stored under this tag (default: the untagged rows).
+	Tag string `json:"tag"`
+}
+
+// LayersRsp is the response from a successful Layers query.
+type LayersRsp struct {
+	NumNodes int `json:"numNodes"` // the number of packages or repositories
+
+	*graph.Layering
+}
@@ -16,10 +16,15 @@ package service
 
 import (
 	"context"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"bitbucket.org/creachadair/stringset"
+	"github.com/creachadair/jrpc2"
+	"github.com/creachadair/jrpc2/code"
 	"github.com/creachadair/repodeps/graph"
-	"github.com/creachadair/repodeps/poll"
 	"github.com/creachadair/repodeps/storage"
 )
 
@@ -27,13 +32,37 @@ import (
 // more rows are available than the limit requested, the response will indicate
 // the next offset of a matching row.
 func (u *Server) Match(ctx context.Context, req *MatchReq) (*MatchRsp, error) {
-	matchPackage, matchRepo, start := req.compile()
+	matchPackage, matchRepo, start := req.compile(u.repoResolver(ctx))
+	filter, err := graph.ParseFilter(req.Query)
+	if err != nil {
+		return nil, jrpc2.Errorf(code.InvalidParams, "invalid query: %v", err)
+	}
+	// If the query fixes a prefix of the import path, no earlier row can match.
+	pfx := filter.Prefix()
+	if strings.HasPrefix(pfx, start) {
+		start = pfx
+	}
 	if req.Limit <= 0 {
 		req.Limit = u.opts.DefaultPageSize
 	}
+	order, err := req.ordering()
+	if err != nil {
+		return nil, err
+	}
+
+	// If a repository is specified, only its rows need be considered.
+	g := u.graph.Tag(req.Tag)
+	scan := scanner(g, req.SnapshotSpec)
+	if req.Repository != "" && req.snapshot().IsZero() {
+		repos := stringset.New(u.repoResolver(ctx)(req.Repository))
+		scan = func(ctx context.Context, start string, f func(*graph.Row) error) error {
+			return u.repoRows(ctx, g, repos, start, f)
+		}
+	}
 
 	rsp := new(MatchRsp)
-	err := u.graph.Scan(ctx, start, func(row *graph.Row) error {
+	var sorted []*graph.Row // if ordered, all the matching rows
+	err = scan(ctx, start, func(row *graph.Row) error {
 		if !matchRepo(row.Repository) {
 			return nil // row does not match
 		} else if !matchPackage(row.ImportPath) {
@@ -41,18 +70,21 @@ func (u *Server) Match(ctx context.Context, req *MatchReq) (*MatchRsp, error) {
 				return storage.ErrStopScan // no more matches are possible
 			}
 			return nil
+		} else if row.Dependents < req.MinDependents || row.DependentRepos < req.MinDependentRepos {
+			return nil // row is below the impact threshold
+		} else if !filter.Match(row) {
+			if row.ImportPath > pfx && !strings.HasPrefix(row.ImportPath, pfx) {
+				return storage.ErrStopScan // no more matches are possible
+			}
+			return nil
 		}
 
 		if req.CountOnly {
 			// do nothing
+		} else if order != nil {
+			sorted = append(sorted, req.trim(row))
 		} else if len(rsp.Rows) < req.Limit {
-			rsp.Rows = append(rsp.Rows, row)
-			if !req.IncludeFiles {
-				row.SourceFiles = nil
-			}
-			if req.ExcludeDirects {
-				row.Directs = nil
-			}
+			rsp.Rows = append(rsp.Rows, req.trim(row))
 		} else {
 			// Found the starting point for the next page.
 			rsp.NextPage = []byte(row.ImportPath)
@@ -61,7 +93,24 @@ func (u *Server) Match(ctx context.Context, req *MatchReq) (*MatchRsp, error) {
 		rsp.NumRows++
 		return nil
 	})
-	return rsp, err
+	if err != nil || order == nil || req.CountOnly {
+		return rsp, err
+	}
+
+	// For an ordered result, the page key is the offset of the next row.
+	sort.SliceStable(sorted, func(i, j int) bool { return order(sorted[i]) > order(sorted[j]) })
+	off, _ := strconv.Atoi(string(req.PageKey))
+	if off > len(sorted) {
+		off = len(sorted)
+	}
+	sorted = sorted[off:]
+	if len(sorted) > req.Limit {
+		rsp.NextPage = []byte(strconv.Itoa(off + req.Limit))
+		sorted = sorted[:req.Limit]
+	}
+	rsp.Rows = sorted
+	rsp.NumRows = len(sorted)
+	return rsp, nil
 }
 
 // MatchReq is the request parameter to the Match method.
@@ -73,6 +122,12 @@ type MatchReq struct {
 	// Match rows with this repository URL.
 	Repository string `json:"repository"`
 
+	// Match rows satisfying this query, in the syntax of graph.ParseFilter.
+	Query string `json:"query"`
+
+	// Match rows stored under this tag (default: the untagged rows).
+	Tag string `json:"tag"`
+
 	// Only count the number of matching rows; do not emit them.
 	CountOnly bool `json:"countOnly"`
 
@@ -82,6 +137,18 @@ type MatchReq struct {
 	// Whether to exclude direct dependencies.
 	ExcludeDirects bool `json:"excludeDirects"`
 
+	// If set, match rows as of this snapshot of the recorded history.
+	SnapshotSpec
+
+	// Match only rows with at least this many transitive dependents, or
+	// dependent repositories. These counts are computed by Impact.
+	MinDependents     int64 `json:"minDependents"`
+	MinDependentRepos int64 `json:"minDependentRepos"`
+
+	// If set, return rows in decreasing order of this field rather than in
+	// order by import path. One of "dependents", "dependentRepos", "ranking".
+	SortBy string `json:"sortBy"`
+
 	// Return at most this many rows (0 uses a reasonable default).
 	Limit int `json:"limit"`
 
@@ -89,33 +156,136 @@ type MatchReq struct {
 	PageKey []byte `json:"pageKey"`
 }
 
-func (m *MatchReq) compile() (mpkg, mrepo func(string) bool, start string) {
-	if t := strings.TrimSuffix(m.Package, "/..."); t != m.Package && t != "" {
-		start = t
-		mpkg = func(pkg string) bool { return pkg == t || strings.HasPrefix(pkg, t+"/") }
-	} else if m.Package != "" {
-		start = m.Package
-		mpkg = func(pkg string) bool { return pkg == m.Package }
-	} else {
-		mpkg = func(string) bool { return true }
-	}
+// compile returns functions to match packages and repository URLs, and the
+// first key at which a match is possible. The resolve function maps a URL to
+// the canonical URL of its repository.
+func (m *MatchReq) compile(resolve func(string) string) (mpkg, mrepo func(string) bool, start string) {
+	mpkg, start = compilePackage(m.Package)
 
 	mrepo = func(string) bool { return true }
 	if m.Repository != "" {
-		fixed := poll.FixRepoURL(m.Repository)
-		mrepo = func(repo string) bool { return repo == fixed }
+		fixed := resolve(m.Repository)
+		mrepo = func(repo string) bool { return resolve(repo) == fixed }
 	}
 
-	if s := string(m.PageKey); s != "" {
+	if s := string(m.PageKey); s != "" && m.SortBy == "" {
 		start = s
 	}
 	return
 }
 
+// ordering returns a function that extracts the sort key of a row, or nil if
+// the rows are to be returned in order by import path.
+func (m *MatchReq) ordering() (func(*graph.Row) float64, error) {
+	switch m.SortBy {
+	case "":
+		return nil, nil
+	case "dependents":
+		return func(row *graph.Row) float64 { return float64(row.Dependents) }, nil
+	case "dependentRepos":
+		return func(row *graph.Row) float64 { return float64(row.DependentRepos) }, nil
+	case "ranking":
+		return func(row *graph.Row) float64 { return row.Ranking }, nil
+	}
+	return nil, jrpc2.Errorf(code.InvalidParams, "unknown sort order %q", m.SortBy)
+}
+
+// trim removes the fields of row that were not requested.
+func (m *MatchReq) trim(row *graph.Row) *graph.Row {
+	if !m.IncludeFiles {
+		row.SourceFiles = nil
+	}
+	if m.ExcludeDirects {
+		row.Directs = nil
+	}
+	return row
+}
+
+// A SnapshotSpec selects a past state of the graph from its recorded history.
+// A zero SnapshotSpec selects the current state.
+type SnapshotSpec struct {
+	// Select the state as of this time.
+	AsOf *time.Time `json:"asOf,omitempty"`
+
+	// Select the state as of this repository commit digest (hex, or a prefix
+	// thereof).
+	Digest string `json:"digest,omitempty"`
+}
+
+func (s SnapshotSpec) snapshot() graph.Snapshot {
+	snap := graph.Snapshot{Digest: s.Digest}
+	if s.AsOf != nil {
+		snap.Time = *s.AsOf
+	}
+	return snap
+}
+
+// scanner returns a function to scan the rows of g as of the specified
+// snapshot.
+func scanner(g *graph.Graph, spec SnapshotSpec) func(context.Context, string, func(*graph.Row) error) error {
+	if snap := spec.snapshot(); !snap.IsZero() {
+		return func(ctx context.Context, start string, f func(*graph.Row) error) error {
+			return g.ScanAt(ctx, snap, start, f)
+		}
+	}
+	return g.Scan
+}
+
+// selectPackages returns the import paths selected by pkgs, together with the
+// packages of repo if it is non-empty. A package ending in "/..." selects the
+// packages of g having that prefix; other packages are selected as given.
+func (u *Server) selectPackages(ctx context.Context, g *graph.Graph, pkgs []string, repo string) ([]string, error) {
+	srcs := stringset.New()
+	var matches []func(string) bool
+	for _, pkg := range pkgs {
+		if !strings.HasSuffix(pkg, "/...") {
+			srcs.Add(pkg)
+			continue
+		}
+		match, _ := compilePackage(pkg)
+		matches = append(matches, match)
+	}
+	resolve := u.repoResolver(ctx)
+	if repo != "" {
+		repo = resolve(repo)
+	}
+	if len(matches) != 0 || repo != "" {
+		if err := g.Scan(ctx, "", func(row *graph.Row) error {
+			if repo != "" && resolve(row.Repository) == repo {
+				srcs.Add(row.ImportPath)
+				return nil
+			}
+			for _, match := range matches {
+				if match(row.ImportPath) {
+					srcs.Add(row.ImportPath)
+					break
+				}
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return srcs.Elements(), nil
+}
+
+// compilePackage returns a function that matches import paths selected by pkg,
+// and the first key that could match. If pkg ends with "/...", any import
+// path with that prefix is matched; if pkg is empty, all paths are matched.
+func compilePackage(pkg string) (match func(string) bool, start string) {
+	if t := strings.TrimSuffix(pkg, "/..."); t != pkg && t != "" {
+		return func(ip string) bool { return ip == t || strings.HasPrefix(ip, t+"/") }, t
+	} else if pkg != "" {
+		return func(ip string) bool { return ip == pkg }, pkg
+	}
+	return func(string) bool { return true }, ""
+}
+
 // MatchRsp is the response from a successful Match query.
 type MatchRsp struct {
 	// The number of rows processed to obtain this result. If countOnly was true
-	// in the request, this is the total number of matching rows.
+	// in the request, this is the total number of matching rows. For a sorted
+	// result, this is the number of rows returned.
 	NumRows int `json:"numRows"`
 
 	Rows     []*graph.Row `json:"rows,omitempty"`
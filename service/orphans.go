@@ -0,0 +1,202 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/creachadair/jrpc2/code"
+	"github.com/creachadair/repodeps/deps"
+	"github.com/creachadair/repodeps/graph"
+	"github.com/creachadair/repodeps/storage"
+)
+
+// The kinds of orphan reported by the Orphans method.
+const (
+	OrphanUnused   = "unused"   // a library package with no importers
+	OrphanBroken   = "broken"   // a program whose dependencies are all missing
+	OrphanInternal = "internal" // a non-internal package imported only from its own repository
+)
+
+// Orphans enumerates packages that appear to be dead: libraries that nothing
+// in the graph imports, programs none of whose dependencies are in the graph,
+// and packages imported only by packages in their own repository.
+func (u *Server) Orphans(ctx context.Context, req *OrphansReq) (*OrphansRsp, error) {
+	want := make(map[string]bool)
+	for _, kind := range req.Kind {
+		switch kind {
+		case OrphanUnused, OrphanBroken, OrphanInternal:
+			want[kind] = true
+		default:
+			return nil, jrpc2.Errorf(code.InvalidParams, "unknown orphan kind %q", kind)
+		}
+	}
+	if len(want) == 0 {
+		want = map[string]bool{OrphanUnused: true, OrphanBroken: true, OrphanInternal: true}
+	}
+	mreq := &MatchReq{Package: req.Package, Repository: req.Repository, PageKey: req.PageKey}
+	resolve := u.repoResolver(ctx)
+	matchPackage, matchRepo, start := mreq.compile(resolve)
+	if req.Limit <= 0 {
+		req.Limit = u.opts.DefaultPageSize
+	}
+
+	// Record the repository of each package, and the importers of each.
+	g := u.graph.Tag(req.Tag)
+	repoOf := make(map[string]string)      // :: import path → repository
+	importers := make(map[string][]string) // :: import path → importers
+	if err := g.Scan(ctx, "", func(row *graph.Row) error {
+		repoOf[row.ImportPath] = resolve(row.Repository)
+		for _, dep := range row.Directs {
+			importers[dep] = append(importers[dep], row.ImportPath)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	// classify reports what kind of orphan row is, or "" if it is not one.
+	classify := func(row *graph.Row) string {
+		imps := importers[row.ImportPath]
+		switch row.Type {
+		case graph.Row_STDLIB:
+			return ""
+		case graph.Row_LIBRARY:
+			if len(imps) == 0 {
+				return OrphanUnused
+			}
+		case graph.Row_PROGRAM:
+			if allMissing(row.Directs, repoOf) {
+				return OrphanBroken
+			}
+			return "" // programs are not imported
+		}
+		if len(imps) == 0 || isInternal(row.ImportPath) {
+			return "" // internal packages are meant to be used locally
+		}
+		own := repoOf[row.ImportPath]
+		for _, imp := range imps {
+			if repoOf[imp] != own {
+				return ""
+			}
+		}
+		return OrphanInternal
+	}
+
+	rsp := new(OrphansRsp)
+	err := g.Scan(ctx, start, func(row *graph.Row) error {
+		if !matchRepo(row.Repository) {
+			return nil // row does not match
+		} else if !matchPackage(row.ImportPath) {
+			if !strings.HasPrefix(row.ImportPath, start) {
+				return storage.ErrStopScan // no more matches are possible
+			}
+			return nil
+		}
+		kind := classify(row)
+		if !want[kind] {
+			return nil
+		}
+
+		if req.CountOnly {
+			// do nothing
+		} else if len(rsp.Orphans) < req.Limit {
+			rsp.Orphans = append(rsp.Orphans, &Orphan{
+				Package:    row.ImportPath,
+				Repository: row.Repository,
+				Kind:       kind,
+				Importers:  len(importers[row.ImportPath]),
+			})
+		} else {
+			rsp.NextPage = []byte(row.ImportPath)
+			return storage.ErrStopScan
+		}
+		rsp.NumOrphans++
+		return nil
+	})
+	return rsp, err
+}
+
+// isInternal reports whether pkg has an "internal" path element.
+func isInternal(pkg string) bool {
+	for _, elt := range strings.Split(pkg, "/") {
+		if elt == "internal" {
+			return true
+		}
+	}
+	return false
+}
+
+// allMissing reports whether pkgs contains at least one import path with a
+// domain, and none of those has a row. Dependencies without a domain, such as
+// the standard library, are not considered, since they are often not indexed.
+func allMissing(pkgs []string, repoOf map[string]string) bool {
+	var n int
+	for _, pkg := range pkgs {
+		if _, ok := deps.HasDomain(pkg); !ok {
+			continue
+		} else if _, ok := repoOf[pkg]; ok {
+			return false
+		}
+		n++
+	}
+	return n != 0
+}
+
+// OrphansReq is the request parameter to the Orphans method.
+type OrphansReq struct {
+	// Check only rows for this package. If package ends with "/...", any row
+	// with that prefix is checked.
+	Package string `json:"package"`
+
+	// Check only rows with this repository URL.
+	Repository string `json:"repository"`
+
+	// Report only these kinds of orphan ("unused", "broken", "internal").
+	// If empty, all kinds are reported.
+	Kind StringList `json:"kind"`
+
+	// Use the rows stored under this tag (default: the untagged rows).
+	Tag string `json:"tag"`
+
+	// Only count the number of orphans; do not emit them.
+	CountOnly bool `json:"countOnly"`
+
+	// Return at most this many orphans (0 uses a reasonable default).
+	Limit int `json:"limit"`
+
+	// Resume reading from this page key.
+	PageKey []byte `json:"pageKey"`
+}
+
+// OrphansRsp is the response from a successful Orphans query.
+type OrphansRsp struct {
+	// The number of orphans processed to obtain this result. If countOnly was
+	// true in the request, this is the total number of orphans.
+	NumOrphans int `json:"numOrphans"`
+
+	Orphans  []*Orphan `json:"orphans,omitempty"`
+	NextPage []byte    `json:"nextPage,omitempty"`
+}
+
+// An Orphan is a single result from the Orphans method.
+type Orphan struct {
+	Package    string `json:"package"`
+	Repository string `json:"repository"`
+	Kind       string `json:"kind"`
+	Importers  int    `json:"importers,omitempty"` // for "internal", the number of importers
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"strings"
+
+	"bitbucket.org/creachadair/stringset"
+	"github.com/creachadair/jrpc2"
+	"github.com/creachadair/jrpc2/code"
+	"github.com/creachadair/repodeps/deps"
+	"github.com/creachadair/repodeps/graph"
+)
+
+// Path finds the shortest chains of direct imports by which one or more
+// packages, or the packages of a repository, depend on a target package.
+func (u *Server) Path(ctx context.Context, req *PathReq) (*PathRsp, error) {
+	if req.To == "" {
+		return nil, jrpc2.Errorf(code.InvalidParams, "missing target package")
+	} else if len(req.From) == 0 && req.Repository == "" {
+		return nil, jrpc2.Errorf(code.InvalidParams, "missing source packages or repository")
+	}
+	g := u.graph.Tag(req.Tag)
+	srcs, err := u.selectPackages(ctx, g, req.From, req.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := g.Paths(ctx, srcs, req.To, &graph.PathOptions{
+		Limit: req.Limit,
+		Skip: func(pkg string, row *graph.Row) bool {
+			if req.ExcludeStdlib && isStdlib(pkg, row) {
+				return true
+			}
+			return req.ExcludeTests && isTestSupport(pkg)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &PathRsp{NumPaths: len(paths), Paths: paths}, nil
+}
+
+// isStdlib reports whether pkg is a standard library package.
+func isStdlib(pkg string, row *graph.Row) bool {
+	if row != nil && row.Type == graph.Row_STDLIB {
+		return true
+	}
+	_, ok := deps.HasDomain(pkg)
+	return !ok
+}
+
+// testElements are the path elements conventionally used by packages that
+// exist only to support tests.
+var testElements = stringset.New("test", "testing", "testutil", "httptest")
+
+// isTestSupport reports whether pkg appears to exist only to support tests.
+// The graph does not record which imports are used only by tests, so this is
+// a heuristic based on package names: pkg is reported if any element of its
+// import path is one of testElements, or ends with "_test".
+func isTestSupport(pkg string) bool {
+	for _, elt := range strings.Split(pkg, "/") {
+		if testElements.Contains(elt) || strings.HasSuffix(elt, "_test") {
+			return true
+		}
+	}
+	return false
+}
+
+// PathReq is the request parameter to the Path method.
+type PathReq struct {
+	// Find chains starting at these packages. If a package ends with "/...",
+	// any package with that prefix is a starting point.
+	From StringList `json:"from"`
+
+	// Find chains starting at any package in this repository.
+	Repository string `json:"repository"`
+
+	// Find chains ending at this package (required).
+	To string `json:"to"`
+
+	// Search the rows stored under this tag (default: the untagged rows).
+	Tag string `json:"tag"`
+
+	// Do not find chains through standard library packages.
+	ExcludeStdlib bool `json:"excludeStdlib"`
+
+	// Do not find chains through packages that appear to support tests,
+	// judging by their import paths. This does not exclude test-only imports
+	// of other packages, which the graph does not record.
+	ExcludeTests bool `json:"excludeTests"`
+
+	// Return at most this many chains, shortest first (0 means 1).
+	Limit int `json:"limit"`
+}
+
+// PathRsp is the response from a successful Path query.
+type PathRsp struct {
+	NumPaths int `json:"numPaths"`
+
+	// Each path begins with a source package and ends with the target.
+	Paths [][]string `json:"paths,omitempty"`
+}
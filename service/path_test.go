@@ -0,0 +1,29 @@
+package service
+
+import "testing"
+
+func TestIsTestSupport(t *testing.T) {
+	tests := []struct {
+		pkg  string
+		want bool
+	}{
+		{"testing", true},
+		{"testing/quick", true},
+		{"net/http/httptest", true},
+		{"github.com/a/b/internal/testutil", true},
+		{"github.com/a/b/test", true},
+		{"github.com/a/b/test/fixtures", true},
+		{"github.com/a/b/foo_test", true},
+
+		{"github.com/a/latest", false},
+		{"github.com/a/attest", false},
+		{"github.com/a/contest/b", false},
+		{"github.com/a/testify", false},
+		{"net/http", false},
+	}
+	for _, test := range tests {
+		if got := isTestSupport(test.pkg); got != test.want {
+			t.Errorf("isTestSupport(%q): got %v, want %v", test.pkg, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"bitbucket.org/creachadair/stringset"
+	cgraph "github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/memstore"
+	"github.com/cayleygraph/cayley/query"
+	"github.com/cayleygraph/quad"
+	"github.com/creachadair/jrpc2"
+	"github.com/creachadair/jrpc2/code"
+	"github.com/creachadair/repodeps/graph"
+	"github.com/creachadair/repodeps/storage"
+	"google.golang.org/protobuf/proto"
+
+	// Register query languages.
+	_ "github.com/cayleygraph/cayley/query/gizmo"
+	_ "github.com/cayleygraph/cayley/query/graphql"
+	_ "github.com/cayleygraph/cayley/query/mql"
+)
+
+// QueryLanguages lists the query languages accepted by the Query method. The
+// first is the default.
+var QueryLanguages = []string{"gizmo", "graphql", "mql"}
+
+// Query evaluates a query against an in-memory quad store that mirrors the
+// untagged view of the graph, using the vocabulary written by quaddeps with
+// stable IRIs. The mirror is built when the server starts, and the rows that
+// have changed since are applied to it in the background at most once per
+// QueryRefresh interval, so a query may not reflect the most recent changes.
+// A query made before the first build is complete waits for it.
+func (u *Server) Query(ctx context.Context, req *QueryReq) (*QueryRsp, error) {
+	if u.mirror == nil {
+		return nil, errors.New("query store is not enabled")
+	}
+	lang := req.Language
+	if lang == "" {
+		lang = QueryLanguages[0]
+	} else if !isQueryLanguage(lang) {
+		return nil, jrpc2.Errorf(code.InvalidParams, "unknown query language %q", lang)
+	}
+	if req.Query == "" {
+		return nil, jrpc2.Errorf(code.InvalidParams, "empty query")
+	}
+	limit := req.Limit
+	if limit <= 0 || limit > u.opts.QueryLimit {
+		limit = u.opts.QueryLimit
+	}
+	timeout := req.Timeout
+	if timeout <= 0 || timeout > u.opts.QueryTimeout {
+		timeout = u.opts.QueryTimeout
+	}
+
+	// The timeout includes the time spent waiting for the store.
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	timedOut := func() error {
+		return jrpc2.Errorf(code.DeadlineExceeded, "query timed out after %v", timeout)
+	}
+
+	qs, release, err := u.mirror.current(ctx)
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, timedOut()
+	} else if err != nil {
+		return nil, fmt.Errorf("building query store: %v", err)
+	}
+	defer release()
+	stats, err := qs.Stats(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	rsp := &QueryRsp{NumQuads: stats.Quads.Size}
+
+	it, err := query.Execute(ctx, qs, lang, req.Query, query.Options{
+		Limit:     limit + 1, // to detect truncation
+		Collation: query.JSON,
+	})
+	if err != nil {
+		return nil, jrpc2.Errorf(code.InvalidParams, "invalid query: %v", err)
+	}
+	defer it.Close()
+	for it.Next(ctx) {
+		res := it.Result()
+		if res == nil {
+			continue // metadata
+		} else if len(rsp.Results) == limit {
+			rsp.Truncated = true
+			break
+		}
+		rsp.Results = append(rsp.Results, res)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, timedOut()
+	} else if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("evaluating query: %v", err)
+	}
+	rsp.Elapsed = time.Since(start)
+	return rsp, nil
+}
+
+func isQueryLanguage(lang string) bool {
+	for _, s := range QueryLanguages {
+		if s == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// QueryReq is the request parameter to the Query method.
+type QueryReq struct {
+	// The query language (see QueryLanguages); empty means "gizmo".
+	Language string `json:"language,omitempty"`
+
+	// The text of the query.
+	Query string `json:"query"`
+
+	// The maximum number of results to report; zero or a value greater than
+	// the server's limit means the server's limit.
+	Limit int `json:"limit,omitempty"`
+
+	// The maximum time to spend evaluating the query; zero or a value greater
+	// than the server's limit means the server's limit.
+	Timeout time.Duration `json:"timeout,omitempty"`
+}
+
+// QueryRsp is the response from a successful Query call.
+type QueryRsp struct {
+	Results   []interface{} `json:"results"`
+	Truncated bool          `json:"truncated,omitempty"` // more results were available
+	NumQuads  int64         `json:"numQuads"`            // size of the query store
+
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// A mirror maintains an in-memory quad store mirroring the contents of the
+// untagged view of a graph. The import paths of rows written in that view are
+// recorded, so that the mirror can tell which packages are out of date. The
+// store is updated in the background by run.
+type mirror struct {
+	dmu   sync.Mutex
+	dirty stringset.Set // import paths of rows written since the last update
+
+	ready chan struct{} // closed when the first build is complete
+	stop  func()        // cancels run
+	done  chan struct{} // closed when run returns
+
+	mu  sync.RWMutex        // exclusive for updates to qs, shared for queries
+	qs  *memstore.QuadStore // the store, once successfully built
+	err error               // the error from the last update, if it failed
+}
+
+func newMirror() *mirror {
+	return &mirror{
+		dirty: stringset.New(),
+		ready: make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// watch returns a wrapper for st that records writes to the mirror.
+func (m *mirror) watch(st storage.Interface) storage.Interface {
+	return mirrorStore{Interface: st, m: m}
+}
+
+// mark records that the row for pkg was written.
+func (m *mirror) mark(pkg string) {
+	m.dmu.Lock()
+	defer m.dmu.Unlock()
+	m.dirty.Add(pkg)
+}
+
+// take returns the recorded writes and clears them.
+func (m *mirror) take() stringset.Set {
+	m.dmu.Lock()
+	defer m.dmu.Unlock()
+	pkgs := m.dirty
+	m.dirty = stringset.New()
+	return pkgs
+}
+
+// start begins building the mirror for g in the background. Thereafter, the
+// mirror checks for writes to g every refresh interval, and updates the store
+// if there were any. The caller must call close to stop the updates.
+func (m *mirror) start(g *graph.Graph, refresh time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.stop = cancel
+	go m.run(ctx, g, refresh)
+}
+
+func (m *mirror) run(ctx context.Context, g *graph.Graph, refresh time.Duration) {
+	defer close(m.done)
+	tick := time.NewTicker(refresh)
+	defer tick.Stop()
+
+	for {
+		// Rows written after this point are recorded for the next update.
+		pkgs := m.take()
+		var err error
+		if m.qs == nil {
+			err = m.build(ctx, g)
+		} else if !pkgs.Empty() {
+			err = m.update(ctx, g, pkgs)
+		}
+		if ctx.Err() != nil {
+			return
+		} else if err != nil && m.qs != nil {
+			for pkg := range pkgs {
+				m.mark(pkg) // retry them at the next update
+			}
+		}
+		m.mu.Lock()
+		m.err = err
+		m.mu.Unlock()
+
+		select {
+		case <-m.ready:
+		default:
+			close(m.ready)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+// build constructs the quad store from the complete contents of g. The cost
+// of a build grows with the size of the graph.
+func (m *mirror) build(ctx context.Context, g *graph.Graph) error {
+	qs := memstore.New()
+	if err := g.EncodeToQuads(ctx, &graph.QuadOptions{StableIRIs: true}, func(q quad.Quad) error {
+		qs.AddQuad(q)
+		return ctx.Err()
+	}); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.qs = qs
+	return nil
+}
+
+// update applies the current rows of pkgs to the quad store, so that it again
+// mirrors g. The cost of an update grows with the number of packages changed,
+// not with the size of the graph. Only run modifies the store, so it may read
+// the store without a lock, but it must wait for running queries to finish
+// before applying the changes.
+func (m *mirror) update(ctx context.Context, g *graph.Graph, pkgs stringset.Set) error {
+	var quads []quad.Quad
+	if err := g.EncodeToQuads(ctx, &graph.QuadOptions{
+		Packages: pkgs.Elements(),
+	}, func(q quad.Quad) error {
+		quads = append(quads, q)
+		return ctx.Err()
+	}); err != nil {
+		return err
+	}
+	tx, err := graph.ReplaceQuads(ctx, m.qs, quads)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.qs.ApplyDeltas(tx.Deltas, cgraph.IgnoreOpts{IgnoreDup: true, IgnoreMissing: true})
+}
+
+// current returns the quad store, waiting for the first build to complete if
+// necessary, or the error from the first build if it failed. If the most
+// recent update failed, the store does not reflect the rows it would have
+// changed until a later update succeeds. The caller must call release when it
+// has finished reading the store, and must not modify it. The store is not
+// updated until the caller releases it.
+func (m *mirror) current(ctx context.Context) (_ *memstore.QuadStore, release func(), _ error) {
+	select {
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case <-m.ready:
+	}
+	m.mu.RLock()
+	if m.qs == nil {
+		defer m.mu.RUnlock()
+		return nil, nil, m.err
+	}
+	return m.qs, m.mu.RUnlock, nil
+}
+
+// close stops the background updates and waits for them to finish.
+func (m *mirror) close() {
+	m.stop()
+	<-m.done
+}
+
+// mirrorStore wraps a storage.Interface to record writes to untagged rows.
+type mirrorStore struct {
+	storage.Interface
+	m *mirror
+}
+
+// Store implements a method of storage.Interface.
+func (s mirrorStore) Store(ctx context.Context, key string, val proto.Message) error {
+	err := s.Interface.Store(ctx, key, val)
+	if err == nil && graph.IsRowKey(key) {
+		s.m.mark(key)
+	}
+	return err
+}
+
+// Delete implements a method of storage.Interface.
+func (s mirrorStore) Delete(ctx context.Context, key string) error {
+	err := s.Interface.Delete(ctx, key)
+	if err == nil && graph.IsRowKey(key) {
+		s.m.mark(key)
+	}
+	return err
+}
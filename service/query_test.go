@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bitbucket.org/creachadair/stringset"
+	"github.com/creachadair/ffs/blob/memstore"
+	"github.com/creachadair/jrpc2/code"
+	"github.com/creachadair/repodeps/deps"
+	"github.com/creachadair/repodeps/graph"
+	"github.com/creachadair/repodeps/storage"
+)
+
+func TestMirrorWrites(t *testing.T) {
+	ctx := context.Background()
+	m := newMirror()
+	g := graph.New(m.watch(storage.NewBlob(memstore.New())), &graph.Options{
+		KeepHistory: true,
+		SearchIndex: true,
+	})
+	if _, err := g.Reindex(ctx); err != nil {
+		t.Fatalf("Reindex failed: %v", err)
+	}
+	repo := &deps.Repo{Remotes: []*deps.Remote{{Name: "origin", Url: "https://x"}}}
+	pkg := &deps.Package{Name: "a", ImportPath: "x/a"}
+	tests := []struct {
+		desc string
+		op   func() error
+		want []string
+	}{
+		{"add untagged row", func() error { return g.Add(ctx, repo, pkg) }, []string{"x/a"}},
+		{"add tagged row", func() error { return g.Tag("v1").Add(ctx, repo, pkg) }, nil},
+		{"remove tagged row", func() error { return g.Tag("v1").Remove(ctx, "x/a") }, nil},
+		{"remove untagged row", func() error { return g.Remove(ctx, "x/a") }, []string{"x/a"}},
+	}
+	for _, test := range tests {
+		if err := test.op(); err != nil {
+			t.Fatalf("%s: %v", test.desc, err)
+		}
+		if got := m.take(); !got.Equals(stringset.New(test.want...)) {
+			t.Errorf("After %s: got writes %v, want %v", test.desc, got, test.want)
+		}
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	// The store is never built, so the query times out waiting for it.
+	u := &Server{mirror: newMirror(), opts: Options{
+		QueryLimit:   1,
+		QueryTimeout: 10 * time.Millisecond,
+	}}
+	rsp, err := u.Query(context.Background(), &QueryReq{Query: "g.V().All()"})
+	if code.FromError(err) != code.DeadlineExceeded {
+		t.Errorf("Query: got %+v, %v; want %v", rsp, err, code.DeadlineExceeded)
+	}
+}
@@ -65,9 +65,14 @@ func (u *Server) Rank(ctx context.Context, req *RankReq) (*RankRsp, error) {
 	}
 
 	// Load and populate the link graph.
+	g := u.graph.Tag(req.Tag)
 	m := make(linkMap)
-	if err := u.graph.Scan(ctx, "", func(row *graph.Row) error {
-		m[row.ImportPath] = &node{cur: 1, links: row.Directs}
+	if err := g.Scan(ctx, "", func(row *graph.Row) error {
+		links := row.Directs
+		if req.ExcludeInternal {
+			links = visibleImports(row)
+		}
+		m[row.ImportPath] = &node{cur: 1, links: links}
 		return nil
 	}); err != nil {
 		return nil, fmt.Errorf("initializing link graph: %v", err)
@@ -109,7 +114,7 @@ func (u *Server) Rank(ctx context.Context, req *RankReq) (*RankRsp, error) {
 		elt.next = math.Trunc(mul * (elt.cur / (max + 1)))
 	}
 
-	err := u.graph.ScanUpdate(ctx, "", func(row *graph.Row) bool {
+	err := g.ScanUpdate(ctx, "", func(row *graph.Row) bool {
 		elt, ok := m[row.ImportPath]
 		isDiff := ok && elt.next != row.Ranking
 		isUpdate := req.Update && isDiff
@@ -129,6 +134,9 @@ func (u *Server) Rank(ctx context.Context, req *RankReq) (*RankRsp, error) {
 
 // RankReq is the request parameter to the Rank method.
 type RankReq struct {
+	// Rank the rows stored under this tag (default: the untagged rows).
+	Tag string `json:"tag"`
+
 	// Number of iterations to compute; > 0 required.
 	Iterations int `json:"iterations"`
 
@@ -141,6 +149,9 @@ type RankReq struct {
 	// Write the updated rankings back to the database.
 	Update bool `json:"update"`
 
+	// Exclude dependencies that violate internal package visibility.
+	ExcludeInternal bool `json:"excludeInternal"`
+
 	LogProgress bool `json:"logProgress"` // push progress notifications
 	LogUpdates  bool `json:"logUpdates"`  // push update notifications
 }
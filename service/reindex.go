@@ -0,0 +1,50 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Reindex rebuilds the indexes for a view of the graph from its current rows.
+// This is needed for a graph whose rows were written before the indexes
+// existed, or before the search index was enabled; until then, lookups by
+// repository scan the rows, and searches find only the rows indexed. It is
+// also needed after the server stops during an update, since a row and its
+// index entries are not written atomically. Updates wait while it runs.
+func (u *Server) Reindex(ctx context.Context, req *ReindexReq) (*ReindexRsp, error) {
+	if u.opts.ReadOnly {
+		return nil, errors.New("database is read-only")
+	}
+	start := time.Now()
+	nr, err := u.graph.Tag(req.Tag).Reindex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ReindexRsp{NumRows: nr, Elapsed: time.Since(start)}, nil
+}
+
+// ReindexReq is the request parameter to the Reindex method.
+type ReindexReq struct {
+	Tag string `json:"tag"` // reindex rows stored under this tag
+}
+
+// ReindexRsp is the response from a successful Reindex call.
+type ReindexRsp struct {
+	NumRows int           `json:"numRows"` // number of rows indexed
+	Elapsed time.Duration `json:"elapsed"`
+}
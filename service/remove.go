@@ -20,7 +20,6 @@ import (
 
 	"bitbucket.org/creachadair/stringset"
 	"github.com/creachadair/repodeps/graph"
-	"github.com/creachadair/repodeps/poll"
 	"github.com/creachadair/repodeps/storage"
 )
 
@@ -39,10 +38,12 @@ func (u *Server) Remove(ctx context.Context, req *RemoveReq) (*RemoveRsp, error)
 			pkgs.Add(pkg)
 		}
 	}
+	resolve := u.repoResolver(ctx)
 	repos := stringset.FromIndexed(len(req.Repository), func(i int) string {
-		return poll.FixRepoURL(req.Repository[i])
+		return resolve(req.Repository[i])
 	})
 	bases := stringset.New()
+	tagged := make(map[string]stringset.Set) // tag → bases
 	for repo := range repos {
 		tags, err := u.repoDB.Tags(ctx, repo)
 		if err != nil {
@@ -54,17 +55,25 @@ func (u *Server) Remove(ctx context.Context, req *RemoveReq) (*RemoveRsp, error)
 				u.pushLog(ctx, req.LogErrors, "log.removeRepo", fmt.Errorf("remove %s: %v", stat.Repository, err))
 			} else {
 				bases.Add(stat.Repository)
+				ts := tagged[stat.Tag]
+				ts.Add(stat.Repository)
+				tagged[stat.Tag] = ts
 			}
 		}
 	}
-	if !req.KeepPackages && len(bases) != 0 {
-		err := u.graph.Scan(ctx, "", func(row *graph.Row) error {
-			if bases.Contains(row.Repository) {
-				if err := u.graph.Remove(ctx, row.ImportPath); err != nil {
-					u.pushLog(ctx, req.LogErrors, "log.removePackage", fmt.Errorf("pkg %s: %v", row.ImportPath, err))
-				} else {
-					pkgs.Add(row.ImportPath)
-				}
+	for tag, repos := range tagged {
+		g := u.graph.Tag(tag)
+		for repo := range repos {
+			g.RemoveRepo(ctx, repo) // best-effort; the record may not exist
+		}
+		if req.KeepPackages {
+			continue
+		}
+		err := u.repoRows(ctx, g, repos, "", func(row *graph.Row) error {
+			if err := g.Remove(ctx, row.ImportPath); err != nil {
+				u.pushLog(ctx, req.LogErrors, "log.removePackage", fmt.Errorf("pkg %s: %v", row.ImportPath, err))
+			} else {
+				pkgs.Add(row.ImportPath)
 			}
 			return nil
 		})
@@ -28,7 +28,15 @@ func (u *Server) RepoStatus(ctx context.Context, req *RepoStatusReq) (*RepoStatu
 	if req.Repository == "" {
 		return nil, jrpc2.Errorf(code.InvalidParams, "empty repository URL")
 	}
-	stats, err := u.repoDB.Tags(ctx, poll.FixRepoURL(req.Repository))
+	url, err := u.repoDB.Resolve(ctx, req.Repository)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := u.repoDB.Tags(ctx, url)
+	if err == storage.ErrKeyNotFound && u.opts.ReadOnly {
+		// A read-only database may not have been migrated to canonical keys.
+		stats, err = u.repoDB.Tags(ctx, poll.FixRepoURL(req.Repository))
+	}
 	if err == storage.ErrKeyNotFound {
 		return nil, jrpc2.Errorf(KeyNotFound, "repo %q not found", req.Repository)
 	} else if err != nil {
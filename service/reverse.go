@@ -19,6 +19,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/creachadair/repodeps/deps"
 	"github.com/creachadair/repodeps/graph"
 	"github.com/creachadair/repodeps/storage"
 )
@@ -26,14 +27,15 @@ import (
 // Reverse enumerates the reverse dependencies of one or more packages.  The
 // order of results is unspecified but deterministic.
 func (u *Server) Reverse(ctx context.Context, req *ReverseReq) (*ReverseRsp, error) {
-	match, filter, err := req.compile(ctx, u.graph)
+	g := u.graph.Tag(req.Tag)
+	match, filter, err := req.compile(ctx, g)
 	if err != nil {
 		return nil, err
 	}
 	if req.Limit <= 0 {
 		req.Limit = u.opts.DefaultPageSize
 	}
-	repo := newRepoMap(ctx, u.graph)
+	repo := newRepoMap(ctx, g)
 	rdep := func(pkg string, row *graph.Row) *ReverseDep {
 		if req.Complete {
 			return &ReverseDep{Target: pkg, Row: row}
@@ -43,7 +45,7 @@ func (u *Server) Reverse(ctx context.Context, req *ReverseReq) (*ReverseRsp, err
 
 	start := string(req.PageKey)
 	rsp := new(ReverseRsp)
-	err = u.graph.Scan(ctx, start, func(row *graph.Row) error {
+	err = g.Scan(ctx, start, func(row *graph.Row) error {
 		// If this row's package does not match the required regexp, skip it.
 		if !filter(row.ImportPath) {
 			return nil
@@ -55,6 +57,8 @@ func (u *Server) Reverse(ctx context.Context, req *ReverseReq) (*ReverseRsp, err
 				continue // not one of the packages we care about
 			} else if req.FilterSameRepo && repo.same(row.ImportPath, dep) {
 				continue // package is in the same repository
+			} else if req.ExcludeInternal && !deps.CanImport(row.ImportPath, dep) {
+				continue // dependency violates internal visibility
 			}
 			hits = append(hits, dep)
 		}
@@ -86,6 +90,9 @@ type ReverseReq struct {
 	// any row with that prefix is matched.
 	Package StringList `json:"package"`
 
+	// Search the rows stored under this tag (default: the untagged rows).
+	Tag string `json:"tag"`
+
 	// Only count the number of matching rows; do not emit them.
 	CountOnly bool `json:"countOnly"`
 
@@ -93,6 +100,9 @@ type ReverseReq struct {
 	// target package or packages.
 	FilterSameRepo bool `json:"filterSameRepo"`
 
+	// Filter out dependencies that violate internal package visibility.
+	ExcludeInternal bool `json:"excludeInternal"`
+
 	// If set, select only reverse dependencies matching this regexp.
 	Matching string `json:"matching"`
 
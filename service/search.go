@@ -0,0 +1,194 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"bitbucket.org/creachadair/stringset"
+	"github.com/creachadair/jrpc2"
+	"github.com/creachadair/jrpc2/code"
+	"github.com/creachadair/repodeps/graph"
+	"github.com/creachadair/repodeps/storage"
+)
+
+// maxSearchCandidates bounds the number of packages collected for each word
+// of a search query while looking for the most selective word.
+const maxSearchCandidates = 10000
+
+// Search finds packages whose names or import paths contain a word beginning
+// with each of the words of the query, using the search index. Results are
+// ordered by how closely they match, then by decreasing ranking, then by
+// import path, so that the most likely completions come first.
+//
+// The candidates are the packages indexed under the most selective word of
+// the query, and each is checked against the other words. If every word
+// matches more than maxSearchCandidates packages, the longest word is used,
+// and all its matches are examined.
+func (u *Server) Search(ctx context.Context, req *SearchReq) (*SearchRsp, error) {
+	if !u.opts.SearchIndex {
+		return nil, errors.New("search index is not enabled")
+	}
+	words := graph.QueryWords(req.Query)
+	if len(words) == 0 {
+		return nil, jrpc2.Errorf(code.InvalidParams, "empty search query")
+	}
+	if req.Limit <= 0 {
+		req.Limit = u.opts.DefaultPageSize
+	}
+
+	g := u.graph.Tag(req.Tag)
+	var cands stringset.Set
+	longest := words[0]
+	for _, word := range words {
+		found, err := searchCandidates(ctx, g, word, maxSearchCandidates)
+		if err != nil {
+			return nil, err
+		} else if found != nil && (cands == nil || len(found) < len(cands)) {
+			cands = found
+		}
+		if len(word) > len(longest) {
+			longest = word
+		}
+	}
+	if cands == nil {
+		found, err := searchCandidates(ctx, g, longest, 0)
+		if err != nil {
+			return nil, err
+		}
+		cands = found
+	}
+
+	rsp := new(SearchRsp)
+	for pkg := range cands {
+		row, err := g.Row(ctx, pkg)
+		if err == storage.ErrKeyNotFound {
+			continue // stale index entry
+		} else if err != nil {
+			return nil, err
+		}
+		score, ok := searchScore(row, words)
+		if !ok {
+			continue
+		}
+		rsp.Results = append(rsp.Results, &SearchResult{
+			ImportPath: row.ImportPath,
+			Name:       row.Name,
+			Repository: row.Repository,
+			Ranking:    row.Ranking,
+			Score:      score,
+		})
+	}
+	sort.Slice(rsp.Results, func(i, j int) bool {
+		a, b := rsp.Results[i], rsp.Results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		} else if a.Ranking != b.Ranking {
+			return a.Ranking > b.Ranking
+		} else if len(a.ImportPath) != len(b.ImportPath) {
+			return len(a.ImportPath) < len(b.ImportPath)
+		}
+		return a.ImportPath < b.ImportPath
+	})
+	rsp.NumMatches = len(rsp.Results)
+	if len(rsp.Results) > req.Limit {
+		rsp.Results = rsp.Results[:req.Limit]
+	}
+	return rsp, nil
+}
+
+// searchCandidates returns the packages indexed under a word beginning with
+// word. If limit > 0 and more than limit packages match, it returns nil.
+func searchCandidates(ctx context.Context, g *graph.Graph, word string, limit int) (stringset.Set, error) {
+	found := stringset.New()
+	var tooMany bool
+	if err := g.Search(ctx, word, func(_, pkg string) error {
+		found.Add(pkg)
+		if limit > 0 && len(found) > limit {
+			tooMany = true
+			return storage.ErrStopScan
+		}
+		return nil
+	}); err != nil || tooMany {
+		return nil, err
+	}
+	return found, nil
+}
+
+// searchScore reports how closely row matches the given search words, and
+// whether it matches them all. For each word, a match of the package name
+// counts 4, a prefix of the name 3, a complete indexed word 2, and a prefix of
+// an indexed word 1.
+func searchScore(row *graph.Row, words []string) (int, bool) {
+	name := strings.ToLower(row.Name)
+	indexed := graph.SearchWords(row)
+	var score int
+	for _, word := range words {
+		switch {
+		case name == word:
+			score += 4
+		case strings.HasPrefix(name, word):
+			score += 3
+		case hasWord(indexed, word):
+			score += 2
+		case hasWordPrefix(indexed, word):
+			score++
+		default:
+			return 0, false
+		}
+	}
+	return score, true
+}
+
+func hasWord(words []string, word string) bool {
+	i := sort.SearchStrings(words, word)
+	return i < len(words) && words[i] == word
+}
+
+func hasWordPrefix(words []string, prefix string) bool {
+	i := sort.SearchStrings(words, prefix)
+	return i < len(words) && strings.HasPrefix(words[i], prefix)
+}
+
+// SearchReq is the request parameter to the Search method.
+type SearchReq struct {
+	// The words to search for, separated by whitespace or punctuation. Case is
+	// not significant.
+	Query string `json:"query"`
+
+	// Search rows stored under this tag (default: the untagged rows).
+	Tag string `json:"tag"`
+
+	// Return at most this many results (0 uses a reasonable default).
+	Limit int `json:"limit"`
+}
+
+// SearchRsp is the response from a successful Search query.
+type SearchRsp struct {
+	NumMatches int             `json:"numMatches"` // total number of matching rows
+	Results    []*SearchResult `json:"results,omitempty"`
+}
+
+// A SearchResult describes a single match of a search query.
+type SearchResult struct {
+	ImportPath string  `json:"importPath"`
+	Name       string  `json:"name,omitempty"`
+	Repository string  `json:"repository,omitempty"`
+	Ranking    float64 `json:"ranking,omitempty"`
+	Score      int     `json:"score"`
+}
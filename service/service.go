@@ -76,6 +76,34 @@ type Options struct {
 	// Open read-only, disallow updates.
 	ReadOnly bool
 
+	// Record the history of graph rows, so that past states can be queried.
+	KeepHistory bool
+
+	// The maximum number of versions of each row to retain (0 means no limit).
+	HistoryVersions int
+
+	// The maximum age of row versions to retain (0 means no limit).
+	HistoryAge time.Duration
+
+	// Maintain an index of package names and import paths, for Search.
+	SearchIndex bool
+
+	// Maintain an in-memory quad store mirroring the graph, to support the
+	// Query method. The store holds the whole untagged graph, and is built
+	// when the server starts, at a cost that grows with the size of the graph.
+	// Thereafter, only the rows that change are applied to it.
+	QueryStore bool
+
+	// The interval at which changed rows are applied to the query store in the
+	// background; zero means 1 minute. Each update waits for running queries.
+	QueryRefresh time.Duration
+
+	// The maximum time allowed for a query to run; zero means 30 seconds.
+	QueryTimeout time.Duration
+
+	// The maximum number of results reported for a query; zero means 1000.
+	QueryLimit int
+
 	// Default package loader options.
 	deps.Options
 }
@@ -87,6 +115,10 @@ func (o Options) merge(opts *deps.Options) *deps.Options {
 		out.UseImportComments = out.UseImportComments || opts.UseImportComments
 		out.TrimRepoPrefix = out.TrimRepoPrefix || opts.TrimRepoPrefix
 		out.StandardLibrary = out.StandardLibrary || opts.StandardLibrary
+		out.SkipSubmodules = out.SkipSubmodules || opts.SkipSubmodules
+		if opts.RemotePolicy != nil {
+			out.RemotePolicy = opts.RemotePolicy
+		}
 	}
 	return &out
 }
@@ -123,6 +155,15 @@ func New(opts Options) (*Server, error) {
 	if opts.DefaultPageSize <= 0 {
 		opts.DefaultPageSize = 100
 	}
+	if opts.QueryTimeout <= 0 {
+		opts.QueryTimeout = 30 * time.Second
+	}
+	if opts.QueryLimit <= 0 {
+		opts.QueryLimit = 1000
+	}
+	if opts.QueryRefresh <= 0 {
+		opts.QueryRefresh = time.Minute
+	}
 	u := &Server{opts: opts}
 	if f := opts.StreamLog; f != nil {
 		mu := new(sync.Mutex)
@@ -148,22 +189,69 @@ func New(opts Options) (*Server, error) {
 		return nil, fmt.Errorf("opening repository database: %v", err)
 	}
 	if s, err := openBadger(opts.GraphDB); err == nil {
-		u.graph = graph.New(storage.NewBlob(s))
+		var st storage.Interface = storage.NewBlob(s)
+		if opts.QueryStore {
+			u.mirror = newMirror()
+			st = u.mirror.watch(st)
+		}
+		u.graph = graph.New(st, &graph.Options{
+			KeepHistory: opts.KeepHistory,
+			MaxVersions: opts.HistoryVersions,
+			MaxAge:      opts.HistoryAge,
+			SearchIndex: opts.SearchIndex,
+		})
 		u.graphC = s
 	} else {
 		u.repoC.Close()
 		return nil, fmt.Errorf("opening graph database: %v", err)
 	}
 
+	// Move statuses written before repository URLs were canonicalized.
+	if !opts.ReadOnly {
+		if _, err := u.repoDB.MigrateKeys(context.Background()); err != nil {
+			u.Close()
+			return nil, fmt.Errorf("migrating repository keys: %v", err)
+		}
+	}
+
+	// If the graph is new, its indexes are complete from the start.
+	if !opts.ReadOnly {
+		if err := u.initIndexes(context.Background(), u.graph); err != nil {
+			u.Close()
+			return nil, fmt.Errorf("initializing indexes: %v", err)
+		}
+	}
+	if u.mirror != nil {
+		u.mirror.start(u.graph, opts.QueryRefresh)
+	}
 	return u, nil
 }
 
+// initIndexes marks the indexes of g as complete if g has no rows, since the
+// indexes are maintained for every row written thereafter. Otherwise, the
+// indexes of g are used only after a call to Reindex.
+func (u *Server) initIndexes(ctx context.Context, g *graph.Graph) error {
+	if ok, err := g.Indexed(ctx); err != nil || ok {
+		return err
+	}
+	empty := true
+	if err := g.List(ctx, "", func(string) error {
+		empty = false
+		return storage.ErrStopScan
+	}); err != nil || !empty {
+		return err
+	}
+	_, err := g.Reindex(ctx)
+	return err
+}
+
 // A Server manages reads and updates to a database of dependencies.
 type Server struct {
 	repoDB *poll.DB
 	repoC  io.Closer
 	graph  *graph.Graph
 	graphC io.Closer
+	mirror *mirror // nil unless opts.QueryStore is set
 
 	scanning int32
 	opts     Options
@@ -173,6 +261,9 @@ type Server struct {
 
 // Close shuts down the server and closes its underlying data stores.
 func (u *Server) Close() error {
+	if u.mirror != nil && u.mirror.stop != nil {
+		u.mirror.close()
+	}
 	gerr := u.graphC.Close()
 	rerr := u.repoC.Close()
 	if gerr != nil {
@@ -184,14 +275,32 @@ func (u *Server) Close() error {
 // Methods returns a method assigner for u.
 func (u *Server) Methods() handler.Map {
 	return handler.Map{
+		"Aggregate":  handler.New(u.Aggregate),
+		"Alias":      handler.New(u.Alias),
+		"Aliases":    handler.New(u.Aliases),
+		"Closure":    handler.New(u.Closure),
+		"Cycles":     handler.New(u.Cycles),
+		"Diff":       handler.New(u.Diff),
+		"Dump":       handler.New(u.Dump),
+		"Fsck":       handler.New(u.Fsck),
+		"Impact":     handler.New(u.Impact),
+		"Layers":     handler.New(u.Layers),
 		"Match":      handler.New(u.Match),
+		"Orphans":    handler.New(u.Orphans),
+		"Path":       handler.New(u.Path),
+		"Query":      handler.New(u.Query),
 		"Rank":       handler.New(u.Rank),
+		"Reindex":    handler.New(u.Reindex),
 		"Remove":     handler.New(u.Remove),
 		"RepoStatus": handler.New(u.RepoStatus),
 		"Resolve":    handler.New(u.Resolve),
+		"Restore":    handler.New(u.Restore),
 		"Reverse":    handler.New(u.Reverse),
 		"Scan":       handler.New(u.Scan),
+		"Search":     handler.New(u.Search),
 		"Update":     handler.New(u.Update),
+		"Vendored":   handler.New(u.Vendored),
+		"Visibility": handler.New(u.Visibility),
 	}
 }
 
@@ -0,0 +1,481 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/creachadair/badgerstore"
+	"github.com/creachadair/repodeps/graph"
+	"github.com/creachadair/repodeps/poll"
+	"github.com/creachadair/repodeps/service"
+	"github.com/creachadair/repodeps/storage"
+)
+
+// newServer constructs a server with databases in a temporary directory. If
+// setup != nil, it is called with the repository database before the server
+// opens it.
+func newServer(t *testing.T, opts service.Options, setup func(*poll.DB)) *service.Server {
+	t.Helper()
+	dir := t.TempDir()
+	opts.RepoDB = filepath.Join(dir, "repo.db")
+	opts.GraphDB = filepath.Join(dir, "graph.db")
+	if setup != nil {
+		s, err := badgerstore.NewPath(opts.RepoDB)
+		if err != nil {
+			t.Fatalf("Opening repository database: %v", err)
+		}
+		setup(poll.NewDB(storage.NewBlob(s)))
+		s.Close()
+	}
+	u, err := service.New(opts)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	t.Cleanup(func() { u.Close() })
+	return u
+}
+
+func TestLegacyStatus(t *testing.T) {
+	ctx := context.Background()
+
+	// Statuses were formerly keyed by the URL as given, without folding case.
+	u := newServer(t, service.Options{}, func(db *poll.DB) {
+		if err := db.Put(ctx, &poll.Status{Repository: "https://github.com/Foo/Bar"}); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	})
+
+	rsp, err := u.RepoStatus(ctx, &service.RepoStatusReq{Repository: "github.com/foo/bar"})
+	if err != nil {
+		t.Fatalf("RepoStatus failed: %v", err)
+	} else if len(rsp.Status) != 1 {
+		t.Fatalf("RepoStatus: got %d statuses, want 1", len(rsp.Status))
+	}
+	if got, want := rsp.Status[0].Prefix, "github.com/Foo/Bar"; got != want {
+		t.Errorf("Status prefix: got %q, want %q", got, want)
+	}
+
+	rm, err := u.Remove(ctx, &service.RemoveReq{Repository: []string{"https://github.com/Foo/Bar"}})
+	if err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	} else if len(rm.Repositories) != 1 {
+		t.Errorf("Remove: got %q, want 1 repository", rm.Repositories)
+	}
+	if _, err := u.RepoStatus(ctx, &service.RepoStatusReq{Repository: "github.com/Foo/Bar"}); err == nil {
+		t.Error("RepoStatus: found a removed repository")
+	}
+}
+
+func TestDiffNoHistory(t *testing.T) {
+	ctx := context.Background()
+	u := newServer(t, service.Options{}, nil)
+
+	now := time.Now()
+	if rsp, err := u.Diff(ctx, &service.DiffReq{
+		From: service.SnapshotSpec{AsOf: &now},
+	}); err == nil {
+		t.Errorf("Diff without history: got %+v, want error", rsp)
+	}
+}
+
+// restore loads the given records into the empty databases of u.
+func restore(t *testing.T, u *service.Server, recs ...*service.DumpRecord) {
+	t.Helper()
+	hdr := &service.DumpRecord{Header: &service.DumpHeader{
+		Format:  service.DumpFormat,
+		Version: service.DumpVersion,
+	}}
+	if _, err := u.Restore(context.Background(), &service.RestoreReq{
+		Records: append([]*service.DumpRecord{hdr}, recs...),
+	}); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+}
+
+// rowKeys returns the import paths of the untagged rows of u.
+func rowKeys(t *testing.T, u *service.Server) []string {
+	t.Helper()
+	var keys []string
+	var pageKey []byte
+	for {
+		rsp, err := u.Dump(context.Background(), &service.DumpReq{PageKey: pageKey})
+		if err != nil {
+			t.Fatalf("Dump failed: %v", err)
+		}
+		for _, rec := range rsp.Records {
+			if rec.Row != nil && rec.Tag == "" {
+				keys = append(keys, rec.Row.ImportPath)
+			}
+		}
+		if rsp.NextPage == nil {
+			return keys
+		}
+		pageKey = rsp.NextPage
+	}
+}
+
+func TestFsckRepairAll(t *testing.T) {
+	ctx := context.Background()
+	u := newServer(t, service.Options{}, nil)
+	restore(t, u,
+		&service.DumpRecord{Status: &poll.Status{Repository: "https://github.com/foo/bar"}},
+		&service.DumpRecord{Status: &poll.Status{Repository: "https://github.com/new/name"}},
+		&service.DumpRecord{Status: &poll.Status{Repository: "https://github.com/no/rows"}},
+		&service.DumpRecord{Alias: &poll.Alias{
+			Alias:      "https://github.com/old/name",
+			Repository: "https://github.com/new/name",
+		}},
+
+		// A row whose repository differs from its status in case.
+		&service.DumpRecord{Row: &graph.Row{
+			ImportPath: "github.com/Foo/Bar",
+			Repository: "https://github.com/Foo/Bar",
+		}},
+		// A row whose repository is an alias.
+		&service.DumpRecord{Row: &graph.Row{
+			ImportPath: "github.com/old/name",
+			Repository: "https://github.com/old/name",
+		}},
+		// A standard library row, which has no status.
+		&service.DumpRecord{Row: &graph.Row{
+			ImportPath: "fmt",
+			Repository: "https://go.googlesource.com/go",
+			Type:       graph.Row_STDLIB,
+		}},
+	)
+
+	rsp, err := u.Fsck(ctx, &service.FsckReq{Repair: []string{"all"}})
+	if err != nil {
+		t.Fatalf("Fsck failed: %v", err)
+	}
+	if rsp.NumProblems != 2 || rsp.NumRepaired != 1 {
+		t.Errorf("Fsck: got %d problems, %d repaired; want 2, 1", rsp.NumProblems, rsp.NumRepaired)
+	}
+	if cls := rsp.Problems[service.OrphanRow]; cls == nil || cls.Count != 1 || cls.Repaired != 0 {
+		t.Errorf("Fsck %s: got %+v, want 1 problem, not repaired", service.OrphanRow, cls)
+	}
+	if cls := rsp.Problems[service.EmptyStatus]; cls == nil || cls.Count != 1 || cls.Repaired != 1 {
+		t.Errorf("Fsck %s: got %+v, want 1 problem, repaired", service.EmptyStatus, cls)
+	}
+
+	got := rowKeys(t, u)
+	want := []string{"fmt", "github.com/Foo/Bar", "github.com/old/name"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Rows after repair: got %q, want %q", got, want)
+	}
+	if _, err := u.RepoStatus(ctx, &service.RepoStatusReq{Repository: "github.com/no/rows"}); err == nil {
+		t.Error("RepoStatus: found a repaired empty status")
+	}
+}
+
+func TestRestoreAliases(t *testing.T) {
+	ctx := context.Background()
+	u := newServer(t, service.Options{}, nil)
+
+	// Restoring the first alias before the second must not resolve it.
+	restore(t, u,
+		&service.DumpRecord{Alias: &poll.Alias{
+			Alias:      "https://github.com/x/mid",
+			Repository: "https://github.com/x/new",
+		}},
+		&service.DumpRecord{Alias: &poll.Alias{
+			Alias:      "https://github.com/x/old",
+			Repository: "https://github.com/x/mid",
+		}},
+	)
+	rsp, err := u.Aliases(ctx)
+	if err != nil {
+		t.Fatalf("Aliases failed: %v", err)
+	}
+	var got []string
+	for _, a := range rsp.Aliases {
+		got = append(got, a.Alias+" → "+a.Repository)
+	}
+	want := []string{
+		"https://github.com/x/mid → https://github.com/x/new",
+		"https://github.com/x/old → https://github.com/x/mid",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Aliases: got %q, want %q", got, want)
+	}
+
+	dump, err := u.Dump(ctx, &service.DumpReq{})
+	if err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	} else if hdr := dump.Records[0].Header; hdr == nil || fmt.Sprint(hdr.Omits) != "[history]" {
+		t.Errorf("Dump header: got %+v, want omits [history]", hdr)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	ctx := context.Background()
+	u := newServer(t, service.Options{
+		QueryStore:   true,
+		QueryRefresh: 10 * time.Millisecond,
+		QueryLimit:   5,
+	}, nil)
+
+	// importers returns the sorted results of a query for the importers of b,
+	// once the store reflects n packages.
+	const importers = `g.V("<dep:pkg/x/b>").In("<dep:imports>").All()`
+	query := func(n int) []string {
+		t.Helper()
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			rsp, err := u.Query(ctx, &service.QueryReq{Query: `g.V().Has("<rdf:type>", "<dep:Package>").All()`})
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			} else if len(rsp.Results) == n {
+				break
+			} else if time.Now().After(deadline) {
+				t.Fatalf("Query: got %d packages, want %d", len(rsp.Results), n)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		rsp, err := u.Query(ctx, &service.QueryReq{Query: importers})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		var got []string
+		for _, res := range rsp.Results {
+			got = append(got, fmt.Sprint(res))
+		}
+		sort.Strings(got)
+		return got
+	}
+
+	restore(t, u,
+		&service.DumpRecord{Row: &graph.Row{ImportPath: "x/a", Repository: "https://x", Directs: []string{"x/b"}}},
+		&service.DumpRecord{Row: &graph.Row{ImportPath: "x/b", Repository: "https://x"}},
+		&service.DumpRecord{Row: &graph.Row{ImportPath: "x/c", Repository: "https://x", Directs: []string{"x/b"}}},
+
+		// Rows stored under a tag are not mirrored.
+		&service.DumpRecord{Tag: "v1", Row: &graph.Row{
+			ImportPath: "x/d", Repository: "https://x", Directs: []string{"x/b"},
+		}},
+	)
+	if got, want := query(3), []string{"map[id:<dep:pkg/x/a>]", "map[id:<dep:pkg/x/c>]"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Importers: got %q, want %q", got, want)
+	}
+
+	if _, err := u.Remove(ctx, &service.RemoveReq{Package: []string{"x/c"}}); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if got, want := query(2), []string{"map[id:<dep:pkg/x/a>]"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Importers after removal: got %q, want %q", got, want)
+	}
+
+	rsp, err := u.Query(ctx, &service.QueryReq{Query: `g.V().All()`, Limit: 1})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	} else if len(rsp.Results) != 1 || !rsp.Truncated {
+		t.Errorf("Query with limit 1: got %d results, truncated=%v; want 1, true", len(rsp.Results), rsp.Truncated)
+	}
+
+	for _, req := range []*service.QueryReq{
+		{Query: ""},
+		{Language: "nonesuch", Query: importers},
+		{Query: "g.V(("},
+	} {
+		if rsp, err := u.Query(ctx, req); err == nil {
+			t.Errorf("Query(%+v): got %+v, want error", req, rsp)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+	u := newServer(t, service.Options{SearchIndex: true}, nil)
+
+	// More rows share the word "common" than are collected for one word, and
+	// only a few of them match the rest of the query.
+	const numCommon = 10050
+	recs := []*service.DumpRecord{
+		{Row: &graph.Row{ImportPath: "golang.org/x/net/http2", Name: "http2", Repository: "https://go.googlesource.com/net"}},
+		{Row: &graph.Row{ImportPath: "golang.org/x/netutil", Name: "netutil", Repository: "https://go.googlesource.com/netutil"}},
+		{Row: &graph.Row{ImportPath: "github.com/y/net", Name: "net", Repository: "https://github.com/y/net"}},
+	}
+	for i := 0; i < numCommon; i++ {
+		name := fmt.Sprintf("p%d", i)
+		if i%1000 == 0 {
+			name = fmt.Sprintf("rare%d", i)
+		}
+		recs = append(recs, &service.DumpRecord{Row: &graph.Row{
+			ImportPath: "example.com/common/" + name,
+			Name:       name,
+			Repository: "https://example.com/common",
+		}})
+	}
+	restore(t, u, recs...)
+
+	tests := []struct {
+		query string
+		want  []string // import paths, in order
+		num   int      // if nonzero, the expected number of matches
+	}{
+		{"x/net", []string{"golang.org/x/netutil", "golang.org/x/net/http2"}, 0},
+		{"golang.org/x/net/http2", []string{"golang.org/x/net/http2"}, 0},
+		{"NET", []string{"github.com/y/net", "golang.org/x/netutil", "golang.org/x/net/http2"}, 0},
+		{"common rare", nil, 11},
+		{"common p1005", []string{"example.com/common/p1005"}, 0},
+		{"common", nil, numCommon},
+		{"nonesuch net", nil, 0},
+	}
+	for _, test := range tests {
+		rsp, err := u.Search(ctx, &service.SearchReq{Query: test.query, Limit: 5})
+		if err != nil {
+			t.Errorf("Search(%q) failed: %v", test.query, err)
+			continue
+		}
+		if test.num != 0 {
+			if rsp.NumMatches != test.num {
+				t.Errorf("Search(%q): got %d matches, want %d", test.query, rsp.NumMatches, test.num)
+			}
+			continue
+		}
+		var got []string
+		for _, res := range rsp.Results {
+			got = append(got, res.ImportPath)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.want) || rsp.NumMatches != len(test.want) {
+			t.Errorf("Search(%q): got %q (%d matches), want %q", test.query, got, rsp.NumMatches, test.want)
+		}
+	}
+}
+
+func TestClosureLimit(t *testing.T) {
+	ctx := context.Background()
+	u := newServer(t, service.Options{DefaultPageSize: 2}, nil)
+	restore(t, u,
+		&service.DumpRecord{Row: &graph.Row{ImportPath: "x/a", Repository: "https://x", Directs: []string{"x/b", "x/c"}}},
+		&service.DumpRecord{Row: &graph.Row{ImportPath: "x/b", Repository: "https://x", Directs: []string{"x/d"}}},
+		&service.DumpRecord{Row: &graph.Row{ImportPath: "x/c", Repository: "https://x"}},
+		&service.DumpRecord{Row: &graph.Row{ImportPath: "x/d", Repository: "https://x"}},
+	)
+	tests := []struct {
+		limit int
+		want  string
+		more  bool
+	}{
+		{0, "[x/a x/b]", true},
+		{3, "[x/a x/b x/c]", true},
+		{-1, "[x/a x/b x/c x/d]", false},
+	}
+	for _, test := range tests {
+		rsp, err := u.Closure(ctx, &service.ClosureReq{Package: []string{"x/a"}, Limit: test.limit})
+		if err != nil {
+			t.Fatalf("Closure(limit=%d) failed: %v", test.limit, err)
+		}
+		var got []string
+		for _, dep := range rsp.Packages {
+			got = append(got, dep.Package)
+		}
+		if fmt.Sprint(got) != test.want || (rsp.NextPage != nil) != test.more || rsp.NumPackages != 4 {
+			t.Errorf("Closure(limit=%d): got %q (%d total, next %q), want %s (more=%v)",
+				test.limit, got, rsp.NumPackages, rsp.NextPage, test.want, test.more)
+		}
+	}
+}
+
+func TestOrphans(t *testing.T) {
+	ctx := context.Background()
+	u := newServer(t, service.Options{}, nil)
+
+	const repoA, repoC = "https://github.com/a/a", "https://github.com/c/c"
+	row := func(pkg, repo string, typ graph.Row_Type, directs ...string) *service.DumpRecord {
+		return &service.DumpRecord{Row: &graph.Row{
+			ImportPath: pkg,
+			Repository: repo,
+			Type:       typ,
+			Directs:    directs,
+		}}
+	}
+	restore(t, u,
+		&service.DumpRecord{Alias: &poll.Alias{
+			Alias:      "https://github.com/b/old",
+			Repository: "https://github.com/b/new",
+		}},
+
+		// A library that nothing imports.
+		row("example.com/a/unused", repoA, graph.Row_LIBRARY),
+
+		// A program whose domain dependencies are all missing. The missing
+		// standard library dependency is not considered.
+		row("example.com/a/cmd/broken", repoA, graph.Row_PROGRAM, "example.com/nonesuch", "fmt"),
+
+		// A program whose dependencies are present.
+		row("example.com/a/cmd/ok", repoA, graph.Row_PROGRAM,
+			"example.com/a/lib", "example.com/a/internal/x", "example.com/c/shared", "os"),
+
+		// Packages imported only from their own repository, one of them
+		// through an alias of its repository URL.
+		row("example.com/a/lib", repoA, graph.Row_LIBRARY),
+		row("example.com/b/lib", "https://github.com/b/new", graph.Row_LIBRARY),
+		row("example.com/b/cmd/user", "https://github.com/b/old", graph.Row_PROGRAM, "example.com/b/lib"),
+
+		// An internal package imported only from its own repository, a
+		// package imported from another repository, and standard library
+		// packages with and without importers.
+		row("example.com/a/internal/x", repoA, graph.Row_LIBRARY),
+		row("example.com/c/shared", repoC, graph.Row_LIBRARY),
+		row("fmt", "https://go.googlesource.com/go", graph.Row_STDLIB),
+		row("os", "https://go.googlesource.com/go", graph.Row_STDLIB),
+	)
+
+	orphans := func(rsp *service.OrphansRsp) []string {
+		var got []string
+		for _, o := range rsp.Orphans {
+			got = append(got, fmt.Sprintf("%s %s %d", o.Kind, o.Package, o.Importers))
+		}
+		return got
+	}
+	want := []string{
+		"broken example.com/a/cmd/broken 0",
+		"internal example.com/a/lib 1",
+		"unused example.com/a/unused 0",
+		"internal example.com/b/lib 1",
+	}
+
+	rsp, err := u.Orphans(ctx, &service.OrphansReq{})
+	if err != nil {
+		t.Fatalf("Orphans failed: %v", err)
+	}
+	if got := orphans(rsp); fmt.Sprint(got) != fmt.Sprint(want) || rsp.NextPage != nil {
+		t.Errorf("Orphans: got %q (next %q), want %q", got, rsp.NextPage, want)
+	}
+
+	// Read the same results in pages of at most 3.
+	var got []string
+	var pageKey []byte
+	for i := 0; ; i++ {
+		rsp, err := u.Orphans(ctx, &service.OrphansReq{Limit: 3, PageKey: pageKey})
+		if err != nil {
+			t.Fatalf("Orphans page %d failed: %v", i, err)
+		} else if len(rsp.Orphans) > 3 {
+			t.Errorf("Orphans page %d: got %d results, want at most 3", i, len(rsp.Orphans))
+		}
+		got = append(got, orphans(rsp)...)
+		if rsp.NextPage == nil {
+			break
+		}
+		pageKey = rsp.NextPage
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Orphans in pages: got %q, want %q", got, want)
+	}
+
+	// Kinds can be selected, and counted without listing them.
+	rsp, err = u.Orphans(ctx, &service.OrphansReq{Kind: []string{service.OrphanInternal}, CountOnly: true})
+	if err != nil {
+		t.Fatalf("Orphans failed: %v", err)
+	} else if rsp.NumOrphans != 2 || len(rsp.Orphans) != 0 {
+		t.Errorf("Orphans internal count: got %d (%d listed), want 2 (0 listed)", rsp.NumOrphans, len(rsp.Orphans))
+	}
+	if _, err := u.Orphans(ctx, &service.OrphansReq{Kind: []string{"nonesuch"}}); err == nil {
+		t.Error("Orphans with an unknown kind: got nil error")
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"log"
 	"os"
 
+	"bitbucket.org/creachadair/stringset"
 	"github.com/creachadair/jrpc2"
 	"github.com/creachadair/jrpc2/code"
 	"github.com/creachadair/repodeps/deps"
@@ -40,11 +41,18 @@ func (u *Server) Update(ctx context.Context, req *UpdateReq) (*UpdateRsp, error)
 	} else if req.CheckOnly && req.Force {
 		return nil, jrpc2.Errorf(code.InvalidParams, "checkOnly and force are mutually exclusive")
 	}
-	repoTag := poll.FixRepoURL(req.Repository)
+	repoTag, err := u.repoDB.Resolve(ctx, req.Repository)
+	if err != nil {
+		return nil, jrpc2.Errorf(code.SystemError, "resolving %s: %v", req.Repository, err)
+	}
+	prefix := req.Prefix
+	if prefix == "" {
+		prefix = poll.CasePrefix(req.Repository, repoTag)
+	}
 	res, err := u.repoDB.Check(ctx, repoTag, &poll.CheckOptions{
 		Reference: req.Reference,
 		Label:     req.Tag,
-		Prefix:    req.Prefix,
+		Prefix:    prefix,
 	})
 	if err != nil {
 		return nil, jrpc2.Errorf(code.SystemError, "checking %s: %v", req.Repository, err)
@@ -67,13 +75,17 @@ func (u *Server) Update(ctx context.Context, req *UpdateReq) (*UpdateRsp, error)
 	}
 
 	if out.NeedsUpdate || req.Force {
+		// Packages for a tagged update are stored separately from the rest.
+		g := u.graph.Tag(req.Tag)
+		if err := u.initIndexes(ctx, g); err != nil {
+			return nil, jrpc2.Errorf(code.SystemError, "initializing indexes: %v", err).WithData(out)
+		}
+
 		// If the caller requested a reset, remove all packages matching this
 		// repository before performing the update.
 		if req.Reset {
-			u.graph.Scan(ctx, "", func(row *graph.Row) error {
-				if row.Repository != res.URL {
-					return nil
-				} else if err := u.graph.Remove(ctx, row.ImportPath); err != nil {
+			u.repoRows(ctx, g, stringset.New(res.URL), "", func(row *graph.Row) error {
+				if err := g.Remove(ctx, row.ImportPath); err != nil {
 					log.Printf("[remove failed] %q: %v", row.ImportPath, err)
 					// TODO: Push back a log notification?
 				}
@@ -82,7 +94,11 @@ func (u *Server) Update(ctx context.Context, req *UpdateReq) (*UpdateRsp, error)
 		}
 
 		// Clone the repository at the target head and update its packages.
-		np, err := u.cloneAndUpdate(ctx, res, u.opts.merge(req.Options))
+		opts := u.opts.merge(req.Options)
+		if opts.PackagePrefix == "" {
+			opts.PackagePrefix = res.Prefix
+		}
+		np, err := u.cloneAndUpdate(ctx, g, res, opts)
 		out.NumPackages = np
 		if err != nil {
 			return nil, jrpc2.Errorf(code.SystemError, "update %s: %v", res.URL, err).WithData(out)
@@ -96,7 +112,10 @@ type UpdateReq struct {
 	// The URL of the repository to update, must be non-empty.
 	Repository string `json:"repository"`
 
-	// The storage tag for this snapshot of the repository (optional).
+	// The storage tag for this snapshot of the repository (optional). The
+	// packages of a tagged snapshot are stored separately from the untagged
+	// graph, and are queried by passing the same tag to Match, Reverse, or
+	// Rank.
 	Tag string `json:"tag"`
 
 	// The reference name to update (optional).
@@ -132,7 +151,7 @@ type UpdateRsp struct {
 	Removed     bool `json:"removed,omitempty"`     // true if removed due to the error limit
 }
 
-func (u *Server) cloneAndUpdate(ctx context.Context, res *poll.CheckResult, opts *deps.Options) (int, error) {
+func (u *Server) cloneAndUpdate(ctx context.Context, g *graph.Graph, res *poll.CheckResult, opts *deps.Options) (int, error) {
 	path, err := os.MkdirTemp(u.opts.WorkDir, res.Digest)
 	if err != nil {
 		return 0, fmt.Errorf("creating clone directory: %v", err)
@@ -147,7 +166,7 @@ func (u *Server) cloneAndUpdate(ctx context.Context, res *poll.CheckResult, opts
 	}
 	var added int
 	for _, repo := range repos {
-		if err := u.graph.AddAll(ctx, repo); err != nil {
+		if err := g.AddAll(ctx, repo); err != nil {
 			return added, err
 		}
 		added += len(repo.Packages)
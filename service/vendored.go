@@ -0,0 +1,109 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/creachadair/jrpc2/code"
+	"github.com/creachadair/repodeps/graph"
+	"github.com/creachadair/repodeps/storage"
+)
+
+// Vendored enumerates the repositories that vendor a module, as recorded by
+// the vendor/modules.txt file of the repository.
+func (u *Server) Vendored(ctx context.Context, req *VendoredReq) (*VendoredRsp, error) {
+	if req.Module == "" {
+		return nil, jrpc2.Errorf(code.InvalidParams, "missing module path")
+	}
+	match, _ := compilePackage(req.Module)
+	if req.Limit <= 0 {
+		req.Limit = u.opts.DefaultPageSize
+	}
+
+	rsp := new(VendoredRsp)
+	err := u.graph.ScanRepos(ctx, string(req.PageKey), func(rec *graph.Repo) error {
+		var hits []*VendoredModule
+		for _, mod := range rec.Vendored {
+			if match(mod.Path) {
+				hits = append(hits, &VendoredModule{
+					Repository: rec.Repository,
+					Module:     mod.Path,
+					Version:    mod.Version,
+					Replace:    mod.Replace,
+					Packages:   mod.Packages,
+				})
+			}
+		}
+		if len(hits) == 0 {
+			return nil
+		}
+		rsp.NumModules += len(hits)
+		if req.CountOnly {
+			return nil
+		} else if len(rsp.Repos) != 0 && len(rsp.Repos)+len(hits) > req.Limit {
+			rsp.NumModules -= len(hits)
+			rsp.NextPage = []byte(rec.Repository)
+			return storage.ErrStopScan
+		}
+		if !req.IncludePackages {
+			for _, hit := range hits {
+				hit.Packages = nil
+			}
+		}
+		rsp.Repos = append(rsp.Repos, hits...)
+		return nil
+	})
+	return rsp, err
+}
+
+// VendoredReq is the request parameter to the Vendored method.
+type VendoredReq struct {
+	// Find repositories vendoring this module path. If module ends with
+	// "/...", any module with that prefix is matched.
+	Module string `json:"module"`
+
+	// Only count the matching vendored modules; do not emit them.
+	CountOnly bool `json:"countOnly"`
+
+	// Whether to include the import paths of vendored packages.
+	IncludePackages bool `json:"includePackages"`
+
+	// Return at most this many results (0 uses a reasonable default).
+	Limit int `json:"limit"`
+
+	// Resume reading from this page key.
+	PageKey []byte `json:"pageKey"`
+}
+
+// VendoredModule records a single module vendored by a repository.
+type VendoredModule struct {
+	Repository string   `json:"repository"`        // the vendoring repository
+	Module     string   `json:"module"`            // the vendored module path
+	Version    string   `json:"version,omitempty"` // the vendored version
+	Replace    string   `json:"replace,omitempty"` // the replacement, if any
+	Packages   []string `json:"packages,omitempty"`
+}
+
+// VendoredRsp is the response from a successful Vendored query.
+type VendoredRsp struct {
+	// The number of matching modules reported, counting each repository that
+	// vendors a matching module once per module it vendors.
+	NumModules int `json:"numModules"`
+
+	Repos    []*VendoredModule `json:"repos,omitempty"`
+	NextPage []byte            `json:"nextPage,omitempty"`
+}
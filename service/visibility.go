@@ -0,0 +1,111 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/creachadair/repodeps/deps"
+	"github.com/creachadair/repodeps/graph"
+	"github.com/creachadair/repodeps/storage"
+)
+
+// Visibility enumerates the direct dependencies recorded in the graph that
+// violate the visibility rules for internal packages. Such edges typically
+// come from forks and copies of other repositories, which the Go toolchain
+// would refuse to build.
+func (u *Server) Visibility(ctx context.Context, req *VisibilityReq) (*VisibilityRsp, error) {
+	match, start := compilePackage(req.Package)
+	if req.Limit <= 0 {
+		req.Limit = u.opts.DefaultPageSize
+	}
+	if s := string(req.PageKey); s != "" {
+		start = s
+	}
+
+	rsp := new(VisibilityRsp)
+	err := u.graph.Scan(ctx, start, func(row *graph.Row) error {
+		if !match(row.ImportPath) {
+			if !strings.HasPrefix(row.ImportPath, start) {
+				return storage.ErrStopScan // no more matches are possible
+			}
+			return nil
+		}
+		var hits []string
+		for _, dep := range row.Directs {
+			if !deps.CanImport(row.ImportPath, dep) {
+				hits = append(hits, dep)
+			}
+		}
+		rsp.NumViolations += len(hits)
+		if req.CountOnly {
+			return nil
+		}
+
+		// As with Reverse, allow the first matching row to exceed the limit so
+		// that we always make progress.
+		if len(rsp.Violations) != 0 && len(rsp.Violations)+len(hits) > req.Limit {
+			rsp.NumViolations -= len(hits)
+			rsp.NextPage = []byte(row.ImportPath)
+			return storage.ErrStopScan
+		}
+		for _, hit := range hits {
+			rsp.Violations = append(rsp.Violations, &ReverseDep{
+				Target: hit,
+				Source: row.ImportPath,
+			})
+		}
+		return nil
+	})
+	return rsp, err
+}
+
+// visibleImports returns the direct dependencies of row that do not violate
+// internal package visibility.
+func visibleImports(row *graph.Row) []string {
+	var out []string
+	for _, dep := range row.Directs {
+		if deps.CanImport(row.ImportPath, dep) {
+			out = append(out, dep)
+		}
+	}
+	return out
+}
+
+// VisibilityReq is the request parameter to the Visibility method.
+type VisibilityReq struct {
+	// Check only the imports of rows matching this package. If package ends
+	// with "/...", any row with that prefix is matched.
+	Package string `json:"package"`
+
+	// Only count the number of violations; do not emit them.
+	CountOnly bool `json:"countOnly"`
+
+	// Return at most this many violations (0 uses a reasonable default).
+	Limit int `json:"limit"`
+
+	// Resume reading from this page key.
+	PageKey []byte `json:"pageKey"`
+}
+
+// VisibilityRsp is the response from a successful Visibility query. Each
+// violation reports a source package that imports an internal target package
+// it is not permitted to see.
+type VisibilityRsp struct {
+	NumViolations int           `json:"numViolations"`
+	Violations    []*ReverseDep `json:"violations,omitempty"`
+	NextPage      []byte        `json:"nextPage,omitempty"`
+}
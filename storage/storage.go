@@ -24,7 +24,8 @@ import (
 )
 
 var (
-	// ErrKeyNotFound is returned by Load when the specified key is not found.
+	// ErrKeyNotFound is returned by Load and Delete when the specified key is
+	// not found.
 	ErrKeyNotFound = errors.New("key not found")
 
 	// ErrStopScan is returned by the callback to Scan to terminate a scan.
@@ -46,6 +47,7 @@ type Interface interface {
 	Scan(ctx context.Context, start string, f func(string) error) error
 
 	// Delete removes the specified key from the database.
+	// If key is not present, Delete must return storage.ErrKeyNotFound.
 	Delete(ctx context.Context, key string) error
 }
 
@@ -95,5 +97,9 @@ func (s BlobStore) Scan(ctx context.Context, start string, f func(string) error)
 
 // Delete implements part of poll.Storage.
 func (s BlobStore) Delete(ctx context.Context, key string) error {
-	return s.bs.Delete(ctx, key)
+	err := s.bs.Delete(ctx, key)
+	if errors.Is(err, blob.ErrKeyNotFound) {
+		return ErrKeyNotFound
+	}
+	return err
 }
@@ -13,7 +13,8 @@
 // limitations under the License.
 
 // Program csv export graph into a CSV in adjacency list format ready to load
-// in Gephi.
+// in Gephi. With -aggregate, it exports the repository or module graph as a
+// weighted edge list instead.
 //
 // See https://gephi.org/users/supported-graph-formats/csv-format
 package main
@@ -39,6 +40,7 @@ var (
 	useIDFile  = flag.String("ids", "", "Use integer IDs for imports and write them to this file")
 	domainOnly = flag.Bool("domain-only", false, "Skip packages without an import domain")
 	skipNoDeps = flag.Bool("skip-no-deps", false, "Skip packages without any dependencies")
+	aggregate  = flag.String("aggregate", "", `Export the aggregated graph ("repository" or "module")`)
 
 	pathID = make(map[string]string) // :: import path → id
 	idFile = io.Discard
@@ -72,6 +74,11 @@ func main() {
 	w.Comma = ';'
 	defer w.Flush()
 
+	if *aggregate != "" {
+		writeAggregate(ctx, c, w)
+		return
+	}
+
 	nr, err := c.Match(ctx, new(service.MatchReq), func(row *graph.Row) error {
 		if _, ok := deps.HasDomain(row.ImportPath); !ok && *domainOnly {
 			return nil
@@ -94,6 +101,21 @@ func main() {
 	log.Printf("Matched %d rows, found %d unique nodes", nr, len(pathID))
 }
 
+// writeAggregate writes the aggregated graph as an edge list with weights.
+func writeAggregate(ctx context.Context, c *client.Client, w *csv.Writer) {
+	rsp, err := c.Aggregate(ctx, &service.AggregateReq{By: *aggregate})
+	if err != nil {
+		log.Fatalf("Aggregate failed: %v", err)
+	}
+	w.Write([]string{"Source", "Target", "Weight"})
+	for _, n := range rsp.Nodes {
+		for _, e := range n.Edges {
+			w.Write([]string{assign(n.Name), assign(e.Target), fmt.Sprint(e.Weight)})
+		}
+	}
+	log.Printf("Aggregated %d %s nodes with %d edges", rsp.NumNodes, rsp.By, rsp.NumEdges)
+}
+
 func assign(path string) string {
 	if *useIDFile == "" {
 		pathID[path] = "" // count only
@@ -0,0 +1,83 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Program cycledeps lists dependency cycles among repositories or modules.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/creachadair/repodeps/client"
+	"github.com/creachadair/repodeps/service"
+)
+
+var (
+	address    = flag.String("address", os.Getenv("DEPSERVER_ADDR"), "Service address")
+	level      = flag.String("by", "repository", `Find cycles among "repository" or "module" nodes`)
+	countOnly  = flag.Bool("count", false, "Count the number of cycles")
+	noEdges    = flag.Bool("no-edges", false, "Omit the package edges that create each cycle")
+	storageTag = flag.String("tag", "", "Search the rows stored under this tag")
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %[1]s [options]
+
+Print the dependency cycles among repositories (or modules), that is, the
+strongly-connected components of the repository graph with more than one
+member. Each output is a JSON text:
+
+   {"nodes": [url, ...], "edges": [{"source": pkg, "target": pkg, "from": url, "to": url}, ...]}
+
+where the edges are the package-level dependencies that connect the members.
+
+Options:
+`, filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	ctx := context.Background()
+	c, err := client.Dial(ctx, *address)
+	if err != nil {
+		log.Fatalf("Dialing service: %v", err)
+	}
+	defer c.Close()
+
+	rsp, err := c.Cycles(ctx, &service.CyclesReq{
+		By:           *level,
+		Tag:          *storageTag,
+		CountOnly:    *countOnly,
+		ExcludeEdges: *noEdges,
+	})
+	if err != nil {
+		log.Fatalf("Cycles failed: %v", err)
+	} else if *countOnly {
+		fmt.Println(rsp.NumCycles)
+		return
+	}
+	enc := json.NewEncoder(os.Stdout)
+	for _, cycle := range rsp.Cycles {
+		enc.Encode(cycle)
+	}
+}
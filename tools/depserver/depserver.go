@@ -31,11 +31,14 @@ import (
 	"github.com/creachadair/jrpc2/jctx"
 	"github.com/creachadair/jrpc2/metrics"
 	"github.com/creachadair/jrpc2/server"
+	"github.com/creachadair/repodeps/deps"
 	"github.com/creachadair/repodeps/service"
+	"github.com/creachadair/repodeps/tools"
 )
 
 var (
-	opts service.Options
+	opts         service.Options
+	remotePolicy deps.RemotePolicy
 
 	serviceAddr = flag.String("address", "", "Service address (required)")
 	repoDB      = os.Getenv("DEPSERVER_REPO_DB")
@@ -57,11 +60,30 @@ func init() {
 	flag.IntVar(&opts.Concurrency, "concurrency", 16, "Maximum concurrent updates")
 	flag.IntVar(&opts.DefaultPageSize, "page-size", 100, "Default result page size")
 	flag.BoolVar(&opts.ReadOnly, "read-only", false, "Open database read-only, disallowing updates")
+	flag.BoolVar(&opts.KeepHistory, "history", false, "Record the history of graph rows")
+	flag.IntVar(&opts.HistoryVersions, "history-versions", 0, "Maximum versions of each row to retain (0 = no limit)")
+	flag.DurationVar(&opts.HistoryAge, "history-age", 0, "Maximum age of row versions to retain (0 = no limit)")
+	flag.BoolVar(&opts.SearchIndex, "search-index", false, "Maintain a search index of package names and paths")
+	flag.BoolVar(&opts.QueryStore, "query-store", false, "Maintain an in-memory quad store of the whole graph for queries")
+	flag.DurationVar(&opts.QueryRefresh, "query-refresh", 5*time.Minute, "Interval between background updates of the query store with changed rows")
+	flag.DurationVar(&opts.QueryTimeout, "query-timeout", 30*time.Second, "Maximum time allowed for a query")
+	flag.IntVar(&opts.QueryLimit, "query-limit", 1000, "Maximum number of results for a query")
 
 	flag.BoolVar(&opts.Options.HashSourceFiles, "hash-source-files", true,
 		"Record source file digests")
 	flag.BoolVar(&opts.Options.UseImportComments, "use-import-comments", true,
 		"Parse import comments to name packages")
+	flag.Func("remote-names", "Prefer remotes with these names (comma-separated)", func(s string) error {
+		remotePolicy.Names = tools.SplitList(s)
+		return nil
+	})
+	flag.Func("remote-hosts", "Consider only remotes on these hosts (comma-separated)", func(s string) error {
+		remotePolicy.Hosts = tools.SplitList(s)
+		return nil
+	})
+	flag.BoolVar(&remotePolicy.MatchModule, "match-module", false,
+		"Prefer remotes matching the root module path")
+	opts.Options.RemotePolicy = &remotePolicy
 }
 
 func main() {
@@ -119,7 +141,7 @@ func main() {
 
 func checkAccess(ctx context.Context, req *jrpc2.Request) error {
 	switch req.Method() {
-	case "Rank", "Remove", "Scan", "Update":
+	case "Alias", "Fsck", "Impact", "Rank", "Reindex", "Remove", "Restore", "Scan", "Update":
 		if writeToken == "" {
 			return nil
 		}
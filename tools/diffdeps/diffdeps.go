@@ -0,0 +1,102 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Program diffdeps reports how the dependency graph changed between two
+// points in its recorded history.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/creachadair/repodeps/client"
+	"github.com/creachadair/repodeps/service"
+)
+
+var (
+	address    = flag.String("address", os.Getenv("DEPSERVER_ADDR"), "Service address")
+	fromSpec   = flag.String("from", "", "Earlier state: an RFC3339 time or a commit digest (required)")
+	toSpec     = flag.String("to", "", "Later state: an RFC3339 time or a commit digest (default current)")
+	externOnly = flag.Bool("external", false, "Report only dependencies outside the matched packages")
+	domainOnly = flag.Bool("domain-only", false, "Report only dependencies that begin with a domain")
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %[1]s [options] -from <spec> [package]
+
+Report the packages and direct dependencies added and removed between two
+states of the graph. Each state is given as an RFC3339 timestamp, or as a
+commit digest (or digest prefix). If a package is given, only rows for that
+package are compared; if it ends with "/...", any row with that prefix is
+compared. The result is printed as a JSON text.
+
+The server must be recording history (depserver -history) for this to work.
+
+Options:
+`, filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+	if *fromSpec == "" {
+		log.Fatal("You must provide a -from state to compare")
+	} else if flag.NArg() > 1 {
+		log.Fatal("You may provide at most one package to match")
+	}
+
+	req := &service.DiffReq{
+		Package:      flag.Arg(0),
+		From:         parseSpec(*fromSpec),
+		ExternalOnly: *externOnly,
+		DomainOnly:   *domainOnly,
+	}
+	if *toSpec != "" {
+		to := parseSpec(*toSpec)
+		req.To = &to
+	}
+
+	ctx := context.Background()
+	c, err := client.Dial(ctx, *address)
+	if err != nil {
+		log.Fatalf("Dialing service: %v", err)
+	}
+	defer c.Close()
+
+	rsp, err := c.Diff(ctx, req)
+	if err != nil {
+		log.Fatalf("Diff failed: %v", err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(rsp); err != nil {
+		log.Fatalf("Encoding result: %v", err)
+	}
+}
+
+// parseSpec interprets s as a timestamp if possible, otherwise as a digest.
+func parseSpec(s string) service.SnapshotSpec {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return service.SnapshotSpec{AsOf: &t}
+	}
+	return service.SnapshotSpec{Digest: s}
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Program dumpdeps writes the contents of the dependency databases as a
+// stream that can be restored by loaddeps.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/creachadair/repodeps/client"
+	"github.com/creachadair/repodeps/service"
+)
+
+var (
+	address  = flag.String("address", os.Getenv("DEPSERVER_ADDR"), "Service address")
+	output   = flag.String("o", "", "Write output to this file (default stdout)")
+	pageSize = flag.Int("page", 1000, "Fetch this many records per request")
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %[1]s [options]
+
+Write every repository status, alias, row, and repository record held by the
+service as a stream of JSON texts, one per line. The first text is a header
+giving the format and schema version of the stream:
+
+   {"header": {"format": "repodeps-dump", "version": 1, "created": time,
+               "omits": ["history"]}}
+
+Each subsequent text has exactly one of the fields "status", "alias", "row",
+or "repo". Rows and repository records also give the "tag" of their view.
+Use loaddeps to restore the stream into an empty database.
+
+Row histories are not included, as the "omits" field of the header records, so
+restoring the stream discards any history recorded by the service.
+
+Options:
+`, filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	ctx := context.Background()
+	c, err := client.Dial(ctx, *address)
+	if err != nil {
+		log.Fatalf("Dialing service: %v", err)
+	}
+	defer c.Close()
+
+	out := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("Creating output: %v", err)
+		}
+		out = f
+	}
+	buf := bufio.NewWriter(out)
+	enc := json.NewEncoder(buf)
+	nr, err := c.Dump(ctx, &service.DumpReq{Limit: *pageSize}, func(rec *service.DumpRecord) error {
+		return enc.Encode(rec)
+	})
+	if err != nil {
+		log.Fatalf("Dump failed: %v", err)
+	} else if err := buf.Flush(); err != nil {
+		log.Fatalf("Writing output: %v", err)
+	} else if err := out.Close(); err != nil {
+		log.Fatalf("Closing output: %v", err)
+	}
+	log.Printf("Wrote %d records", nr)
+}
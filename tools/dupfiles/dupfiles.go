@@ -38,43 +38,28 @@ func main() {
 	}
 	defer c.Close()
 
-	type loc struct {
-		Repo       string `json:"repo"`
-		Name       string `json:"path"`
-		ImportPath string `json:"importPath"`
-	}
-	dups := make(map[string][]loc)
-
 	ctx := context.Background()
-	if err := g.Scan(ctx, "", func(row *graph.Row) error {
-		for _, file := range row.SourceFiles {
-			digest := string(file.Digest)
-			elt := dups[digest]
-			v := loc{
-				Repo:       row.Repository,
-				Name:       filepath.Base(file.RepoPath),
-				ImportPath: row.ImportPath,
-			}
-			if elt == nil {
-				dups[digest] = []loc{v}
-			} else {
-				dups[digest] = append(elt, v)
-			}
-		}
-		return nil
-	}); err != nil {
-		log.Fatalf("Scan failed: %v", err)
-	}
-
+	repoOf := make(map[string]string) // :: import path → repository
 	tw := tabwriter.NewWriter(os.Stdout, 4, 8, 1, ' ', 0)
-	for digest, locs := range dups {
-		if len(locs) < 2 {
-			continue
+	if err := g.ScanDigests(ctx, func(digest []byte, files []*graph.FileRef) error {
+		if len(files) < 2 {
+			return nil
 		}
 		fmt.Fprintf(tw, "%x\n", digest)
-		for _, elt := range locs {
-			fmt.Fprintf(tw, "\t%s\t%s\t%s\n", elt.Name, elt.Repo, elt.ImportPath)
+		for _, file := range files {
+			repo, ok := repoOf[file.ImportPath]
+			if !ok {
+				row, err := g.Row(ctx, file.ImportPath)
+				if err != nil {
+					return fmt.Errorf("loading %q: %v", file.ImportPath, err)
+				}
+				repo = row.Repository
+				repoOf[file.ImportPath] = repo
+			}
+			fmt.Fprintf(tw, "\t%s\t%s\t%s\n", filepath.Base(file.RepoPath), repo, file.ImportPath)
 		}
-		tw.Flush()
+		return tw.Flush()
+	}); err != nil {
+		log.Fatalf("Scan failed: %v", err)
 	}
 }
@@ -0,0 +1,112 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Program exportdeps writes a subgraph of the dependency graph in a format
+// for visualization tools.
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/creachadair/repodeps/export"
+	"github.com/creachadair/repodeps/tools"
+)
+
+var (
+	graphDB    = flag.String("graph-db", os.Getenv("DEPSERVER_DB"), "Graph database path (required)")
+	format     = flag.String("format", "graphml", "Output format ("+formatNames()+")")
+	outputPath = flag.String("output", "", "Write output to this file (default stdout)")
+	prefix     = flag.String("prefix", "", "Select packages whose import paths have this prefix")
+	roots      = flag.String("root", "", "Comma-separated packages to select")
+	maxDepth   = flag.Int("depth", 0, "Also select dependencies up to this many steps away")
+	leaves     = flag.Bool("leaves", false, "Include the direct dependencies of selected packages")
+	storageTag = flag.String("tag", "", "Use the rows stored under this tag")
+)
+
+func formatNames() string {
+	var names []string
+	for name := range export.Formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %[1]s [options]
+
+Write a subgraph of the dependency graph in a format for visualization tools.
+The subgraph contains the packages matching -prefix and those named by -root;
+if neither is set, it contains every package. With -depth, the dependencies of
+those packages are included up to the given distance. Only the edges among the
+selected packages are written, unless -leaves is set.
+
+Each node carries its package type, repository, module, and ranking as
+attributes. DOT output is intended for small subgraphs.
+
+Options:
+`, filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+	if _, ok := export.Formats[*format]; !ok {
+		log.Fatalf("Unknown -format %q (want one of %s)", *format, formatNames())
+	}
+	g, c, err := tools.OpenGraph(*graphDB)
+	if err != nil {
+		log.Fatalf("Opening graph: %v", err)
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+	sg, err := export.Select(ctx, g.Tag(*storageTag), &export.Options{
+		Prefix:   *prefix,
+		Roots:    tools.SplitList(*roots),
+		MaxDepth: *maxDepth,
+		Leaves:   *leaves,
+	})
+	if err != nil {
+		log.Fatalf("Selecting subgraph: %v", err)
+	}
+
+	out := os.Stdout
+	if *outputPath != "" {
+		f, err := os.Create(*outputPath)
+		if err != nil {
+			log.Fatalf("Creating output: %v", err)
+		}
+		out = f
+	}
+	buf := bufio.NewWriter(out)
+	if err := export.Write(buf, *format, sg); err != nil {
+		log.Fatalf("Writing output: %v", err)
+	} else if err := buf.Flush(); err != nil {
+		log.Fatalf("Writing output: %v", err)
+	} else if err := out.Close(); err != nil {
+		log.Fatalf("Closing output: %v", err)
+	}
+	log.Printf("Wrote %d nodes and %d edges", len(sg.Nodes), len(sg.Edges))
+}
@@ -0,0 +1,108 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Program fsckdeps checks the consistency of the dependency databases.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/creachadair/repodeps/client"
+	"github.com/creachadair/repodeps/service"
+	"github.com/creachadair/repodeps/tools"
+)
+
+var (
+	address     = flag.String("address", os.Getenv("DEPSERVER_ADDR"), "Service address")
+	repairKinds = flag.String("repair", "", `Comma-separated kinds of problem to repair ("all" for every kind except orphanRow)`)
+	maxExamples = flag.Int("examples", 0, "Report at most this many examples of each kind (default 10)")
+	verbose     = flag.Bool("v", false, "Print each problem as it is found")
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %[1]s [options]
+
+Check the consistency of the graph and repository databases, and optionally
+repair the problems found. Every tagged view of the graph is checked. The
+kinds of problem are:
+
+   badRow       -- a row that cannot be decoded (repair: delete)
+   keyMismatch  -- a row stored under a key other than its import path
+                   (repair: move the row to its import path)
+   badRepo      -- a repository record that cannot be decoded (repair: delete)
+   emptyRepo    -- a repository record with no rows (repair: delete)
+   badVersion   -- a history record that cannot be decoded (repair: delete)
+   badStatus    -- a repository status that cannot be decoded (repair: delete)
+   orphanRow    -- a row whose repository has no status (repair: delete)
+   emptyStatus  -- a repository status with no rows (repair: delete)
+
+Rows added without a repository update, such as the standard library or the
+packages of git submodules, are reported as orphanRow. For this reason, -repair
+all does not repair that kind; it must be named explicitly.
+
+The summary is written to stdout as a JSON text. With -v, each problem is also
+written to stderr as it is found. The exit status is 1 if any problem was not
+repaired.
+
+Options:
+`, filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	ctx := context.Background()
+	c, err := client.Dial(ctx, *address)
+	if err != nil {
+		log.Fatalf("Dialing service: %v", err)
+	}
+	defer c.Close()
+	c.SetToken(os.Getenv("DEPSERVER_WRITE_TOKEN"))
+
+	if *verbose {
+		go c.Receive(ctx, func(req *jrpc2.Request) {
+			var p service.FsckProblem
+			if err := req.UnmarshalParams(&p); err == nil && p.Problem != nil {
+				log.Printf("%s [tag %q]: %q %s", p.Kind, p.Tag, p.Key, p.Detail)
+			}
+		})
+	}
+	rsp, err := c.Fsck(ctx, &service.FsckReq{
+		Repair:      tools.SplitList(*repairKinds),
+		MaxExamples: *maxExamples,
+		LogProblems: *verbose,
+	})
+	if err != nil {
+		log.Fatalf("Fsck failed: %v", err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(rsp); err != nil {
+		log.Fatalf("Encoding result: %v", err)
+	}
+	if rsp.NumProblems > rsp.NumRepaired {
+		c.Close()
+		os.Exit(1) // report unrepaired problems in the exit status
+	}
+}
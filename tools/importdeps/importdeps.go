@@ -0,0 +1,147 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Program importdeps loads rows from RDF N-quads, as written by quaddeps, into
+// the graph held by the service.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/creachadair/repodeps/client"
+	"github.com/creachadair/repodeps/graph"
+	"github.com/creachadair/repodeps/service"
+)
+
+var (
+	address    = flag.String("address", os.Getenv("DEPSERVER_ADDR"), "Service address")
+	batchSize  = flag.Int("batch", 1000, "Send this many rows per request")
+	keepRows   = flag.Bool("keep", false, "Do not replace rows that already exist")
+	storageTag = flag.String("tag", "", "Store the rows under this tag")
+	dryRun     = flag.Bool("dry-run", false, "Decode the input and count rows, but do not store them")
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %[1]s [options] [quads-file]
+
+Decode rows from RDF N-quads in the vocabulary written by quaddeps, and store
+them in the graph held by the service. If no file is given, the quads are read
+from stdin. Existing rows with the same import path are replaced, unless -keep
+is set. Packages marked dep:is-missing are not stored.
+
+Packages marked dep:is-removed, as in the output of quaddeps -since, are
+removed from the graph after the rows are stored. The service removes packages
+only from the untagged graph, so with -tag the removed packages are reported
+but not removed.
+
+The quads may come in any order, so the entire input is decoded before any rows
+are stored.
+
+Options:
+`, filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() > 1 {
+		log.Fatal("You may provide at most one input file")
+	}
+	in := os.Stdin
+	if flag.NArg() == 1 {
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			log.Fatalf("Opening input: %v", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	dec := graph.NewQuadDecoder()
+	if err := dec.Read(in); err != nil {
+		log.Fatalf("Reading quads: %v", err)
+	}
+	var rows []*graph.Row
+	if err := dec.Rows(func(row *graph.Row) error {
+		rows = append(rows, row)
+		return nil
+	}); err != nil {
+		log.Fatalf("Decoding quads: %v", err)
+	}
+	var removed []string
+	dec.Removed(func(pkg string) error {
+		removed = append(removed, pkg)
+		return nil
+	})
+	log.Printf("Decoded %d rows, %d removed packages", len(rows), len(removed))
+	if *dryRun {
+		return
+	}
+
+	ctx := context.Background()
+	c, err := client.Dial(ctx, *address)
+	if err != nil {
+		log.Fatalf("Dialing service: %v", err)
+	}
+	defer c.Close()
+	c.SetToken(os.Getenv("DEPSERVER_WRITE_TOKEN"))
+
+	var stored, skipped int
+	for len(rows) != 0 {
+		n := *batchSize
+		if n <= 0 || n > len(rows) {
+			n = len(rows)
+		}
+		req := &service.RestoreReq{Append: true, Keep: *keepRows}
+		for _, row := range rows[:n] {
+			req.Records = append(req.Records, &service.DumpRecord{Tag: *storageTag, Row: row})
+		}
+		rsp, err := c.Restore(ctx, req)
+		if err != nil {
+			log.Fatalf("Storing rows: %v", err)
+		}
+		stored += rsp.NumRows
+		skipped += rsp.NumSkipped
+		rows = rows[n:]
+	}
+	log.Printf("Stored %d rows, kept %d existing rows", stored, skipped)
+
+	if len(removed) == 0 {
+		return
+	} else if *storageTag != "" {
+		log.Printf("Not removing %d packages from tag %q: %q", len(removed), *storageTag, removed)
+		return
+	}
+	var numRemoved int
+	for len(removed) != 0 {
+		n := *batchSize
+		if n <= 0 || n > len(removed) {
+			n = len(removed)
+		}
+		rsp, err := c.Remove(ctx, &service.RemoveReq{Package: removed[:n]})
+		if err != nil {
+			log.Fatalf("Removing packages: %v", err)
+		}
+		numRemoved += len(rsp.Packages)
+		removed = removed[n:]
+	}
+	log.Printf("Removed %d packages", numRemoved)
+}
@@ -0,0 +1,83 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Program internaldeps lists dependencies that violate the visibility rules
+// for internal packages.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/creachadair/repodeps/client"
+	"github.com/creachadair/repodeps/service"
+)
+
+var (
+	address   = flag.String("address", os.Getenv("DEPSERVER_ADDR"), "Service address")
+	countOnly = flag.Bool("count", false, "Count the number of violations")
+	limit     = flag.Int("limit", 0, "Return at most this many results")
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %[1]s [options] [package]
+
+Print the direct dependencies recorded in the graph that violate the visibility
+rules for internal packages. If a package is given, only the imports of that
+package are checked; if it ends with "/...", it matches any package with the
+given prefix. Each output is a JSON text:
+
+   {"target": target-package, "source": source-package}
+
+where source-package is the importing package and target-package is the
+internal package it is not permitted to import.
+
+Options:
+`, filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	ctx := context.Background()
+	c, err := client.Dial(ctx, *address)
+	if err != nil {
+		log.Fatalf("Dialing service: %v", err)
+	}
+	defer c.Close()
+
+	enc := json.NewEncoder(os.Stdout)
+	nr, err := c.Visibility(ctx, &service.VisibilityReq{
+		Package:   flag.Arg(0),
+		CountOnly: *countOnly,
+		Limit:     *limit,
+	}, func(dep *service.ReverseDep) error {
+		return enc.Encode(dep)
+	})
+	if err != nil {
+		log.Printf("Visibility failed: %v", err)
+	} else if *countOnly {
+		fmt.Println(nr)
+	} else if nr == 0 {
+		log.Print("No visibility violations found")
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Program loaddeps restores the dependency databases from a stream written by
+// dumpdeps.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/creachadair/repodeps/client"
+	"github.com/creachadair/repodeps/service"
+)
+
+var (
+	address   = flag.String("address", os.Getenv("DEPSERVER_ADDR"), "Service address")
+	batchSize = flag.Int("batch", 1000, "Send this many records per request")
+	doAppend  = flag.Bool("append", false, "Add to a database that is not empty")
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %[1]s [options] [dump-file]
+
+Restore the records of a stream written by dumpdeps into the databases held by
+the service. If no file is given, the stream is read from stdin. The databases
+must be empty unless -append is set.
+
+If a restore fails partway, the databases are left partly restored. Restoring
+a record again replaces it with the same value, so the restore may be retried
+from the beginning of the stream with -append. Alternatively, stop the server,
+delete its databases, and start over.
+
+Options:
+`, filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() > 1 {
+		log.Fatal("You may provide at most one dump file")
+	}
+
+	in := os.Stdin
+	if flag.NArg() == 1 {
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			log.Fatalf("Opening input: %v", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	dec := json.NewDecoder(in)
+	var hdr service.DumpRecord
+	if err := dec.Decode(&hdr); err != nil {
+		log.Fatalf("Reading header: %v", err)
+	} else if hdr.Header == nil {
+		log.Fatal("Input does not begin with a dump header")
+	} else if hdr.Header.Format != service.DumpFormat {
+		log.Fatalf("Unknown dump format %q", hdr.Header.Format)
+	} else if hdr.Header.Version > service.DumpVersion {
+		log.Fatalf("Dump version %d is newer than supported (%d)", hdr.Header.Version, service.DumpVersion)
+	} else if len(hdr.Header.Omits) != 0 {
+		log.Printf("Note: the dump does not include %s", strings.Join(hdr.Header.Omits, ", "))
+	}
+
+	ctx := context.Background()
+	c, err := client.Dial(ctx, *address)
+	if err != nil {
+		log.Fatalf("Dialing service: %v", err)
+	}
+	defer c.Close()
+	c.SetToken(os.Getenv("DEPSERVER_WRITE_TOKEN"))
+
+	var total service.RestoreRsp
+	req := &service.RestoreReq{Records: []*service.DumpRecord{&hdr}, Append: *doAppend}
+	send := func() {
+		rsp, err := c.Restore(ctx, req)
+		if err != nil {
+			log.Fatalf("Restore failed: %v", err)
+		}
+		total.NumStatuses += rsp.NumStatuses
+		total.NumAliases += rsp.NumAliases
+		total.NumRows += rsp.NumRows
+		total.NumRepos += rsp.NumRepos
+		req = &service.RestoreReq{Append: true}
+	}
+	for {
+		var rec service.DumpRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			break
+		} else if err != nil {
+			log.Fatalf("Reading input: %v", err)
+		}
+		req.Records = append(req.Records, &rec)
+		if len(req.Records) >= *batchSize {
+			send()
+		}
+	}
+	if len(req.Records) != 0 {
+		send()
+	}
+	log.Printf("Restored %d statuses, %d aliases, %d rows, %d repositories",
+		total.NumStatuses, total.NumAliases, total.NumRows, total.NumRepos)
+}
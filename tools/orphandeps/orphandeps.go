@@ -0,0 +1,92 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Program orphandeps lists packages that appear to be unused.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/creachadair/repodeps/client"
+	"github.com/creachadair/repodeps/service"
+	"github.com/creachadair/repodeps/tools"
+)
+
+var (
+	address    = flag.String("address", os.Getenv("DEPSERVER_ADDR"), "Service address")
+	matchRepo  = flag.String("repo", "", "Check only packages in this repository")
+	kinds      = flag.String("kind", "", "Comma-separated kinds of orphan to report (default all)")
+	countOnly  = flag.Bool("count", false, "Count the number of orphans")
+	limit      = flag.Int("limit", 0, "Return at most this many results")
+	storageTag = flag.String("tag", "", "Use the rows stored under this tag")
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %[1]s [options] [package]
+
+Print packages that appear to be dead. If a package is given, only packages
+matching it are checked; if it ends with "/...", any package with that prefix
+is checked. The kinds of orphan are:
+
+   unused    -- a library package that nothing in the graph imports
+   broken    -- a program none of whose (non-stdlib) dependencies are indexed
+   internal  -- a non-internal package imported only from its own repository
+
+Each output is a JSON text:
+
+   {"package": path, "repository": url, "kind": kind}
+
+Options:
+`, filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() > 1 {
+		log.Fatal("You may provide at most one package to match")
+	}
+
+	ctx := context.Background()
+	c, err := client.Dial(ctx, *address)
+	if err != nil {
+		log.Fatalf("Dialing service: %v", err)
+	}
+	defer c.Close()
+
+	enc := json.NewEncoder(os.Stdout)
+	nr, err := c.Orphans(ctx, &service.OrphansReq{
+		Package:    flag.Arg(0),
+		Repository: *matchRepo,
+		Kind:       tools.SplitList(*kinds),
+		Tag:        *storageTag,
+		CountOnly:  *countOnly,
+		Limit:      *limit,
+	}, func(orphan *service.Orphan) error {
+		return enc.Encode(orphan)
+	})
+	if err != nil {
+		log.Fatalf("Orphans failed: %v", err)
+	} else if *countOnly {
+		fmt.Println(nr)
+	}
+}
@@ -12,26 +12,49 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Program quaddeps compiles a graph into RDF triples.
+// Program quaddeps compiles a graph into RDF triples. With -aggregate, it
+// compiles the repository or module graph instead.
+//
+// With -stable, packages and files are identified by IRIs derived from their
+// import paths and digests rather than by blank nodes. With -since, only the
+// rows changed since the given time are compiled, using stable IRIs; this
+// requires a graph whose history is recorded.
+//
+// The output of -since may be loaded into an existing -output store. Each
+// changed package replaces every quad whose subject is that package, and
+// files, repositories, and removed packages no longer referred to are deleted,
+// so that the store matches a compilation of the whole graph. Rankings
+// assigned by Rank or Impact are not recorded in the history, so they are
+// current only for the changed packages; compile the whole graph to refresh
+// them.
 package main
 
 import (
 	"context"
 	"flag"
+	"io"
 	"log"
 	"os"
+	"time"
 
 	"github.com/cayleygraph/cayley"
 	"github.com/cayleygraph/cayley/graph"
 	"github.com/cayleygraph/cayley/graph/kv/bolt"
+	"github.com/cayleygraph/quad"
+	rgraph "github.com/creachadair/repodeps/graph"
 	"github.com/creachadair/repodeps/tools"
 )
 
 var (
 	graphDB    = flag.String("graph-db", os.Getenv("DEPSERVER_DB"), "Graph database path (required)")
 	outputPath = flag.String("output", "", "Output storage path (optional)")
+	aggregate  = flag.String("aggregate", "", `Compile the aggregated graph ("repository" or "module")`)
+	stableIRIs = flag.Bool("stable", false, "Identify packages and files by stable IRIs")
+	sinceTime  = flag.String("since", "", "Compile only rows changed since this time (RFC3339)")
 )
 
+type encodeFunc = func(context.Context, *rgraph.QuadOptions, func(quad.Quad) error) error
+
 func main() {
 	flag.Parse()
 	g, c, err := tools.OpenGraph(*graphDB)
@@ -40,10 +63,43 @@ func main() {
 	}
 	defer c.Close()
 
+	opts := &rgraph.QuadOptions{StableIRIs: *stableIRIs}
+	if *sinceTime != "" {
+		t, err := time.Parse(time.RFC3339, *sinceTime)
+		if err != nil {
+			log.Fatalf("Invalid -since: %v", err)
+		}
+		opts.Since = t
+	}
+
 	ctx := context.Background()
+	var encode encodeFunc = g.EncodeToQuads
+	write := g.WriteQuads
+	if *aggregate != "" {
+		if *stableIRIs || *sinceTime != "" {
+			log.Fatal("The -stable and -since flags do not apply to -aggregate")
+		}
+		level, err := rgraph.ParseLevel(*aggregate)
+		if err != nil {
+			log.Fatalf("Invalid -aggregate: %v", err)
+		}
+		nodes, err := g.Aggregate(ctx, level)
+		if err != nil {
+			log.Fatalf("Aggregating graph: %v", err)
+		}
+		encode = func(_ context.Context, _ *rgraph.QuadOptions, f func(quad.Quad) error) error {
+			return rgraph.EncodeAggregateToQuads(level, nodes, f)
+		}
+		write = func(_ context.Context, w io.Writer, _ *rgraph.QuadOptions) error {
+			return rgraph.WriteAggregateQuads(w, level, nodes)
+		}
+	}
+
 	var werr error
 	if *outputPath != "" {
-		if err := graph.InitQuadStore(bolt.Type, *outputPath, nil); err != nil {
+		// An incremental update may be applied to an existing store.
+		if err := graph.InitQuadStore(bolt.Type, *outputPath, nil); err != nil &&
+			!(err == graph.ErrDatabaseExists && !opts.Since.IsZero()) {
 			log.Fatalf("Initializing output: %v", err)
 		}
 		st, err := cayley.NewGraph(bolt.Type, *outputPath, nil)
@@ -55,11 +111,33 @@ func main() {
 				log.Fatalf("Closing output: %v", err)
 			}
 		}()
-		werr = g.EncodeToQuads(ctx, st.QuadWriter.AddQuad)
+		if opts.Since.IsZero() {
+			werr = encode(ctx, opts, st.QuadWriter.AddQuad)
+		} else {
+			werr = applyChanges(ctx, st, opts, encode)
+		}
 	} else {
-		werr = g.WriteQuads(ctx, os.Stdout)
+		werr = write(ctx, os.Stdout, opts)
 	}
 	if werr != nil {
-		log.Fatalf("Writing output: %v", err)
+		log.Fatalf("Writing output: %v", werr)
+	}
+}
+
+// applyChanges encodes the rows changed since opts.Since and applies them to
+// st in a single transaction, replacing the existing quads of each package
+// the changes describe.
+func applyChanges(ctx context.Context, st *cayley.Handle, opts *rgraph.QuadOptions, encode encodeFunc) error {
+	var quads []quad.Quad
+	if err := encode(ctx, opts, func(q quad.Quad) error {
+		quads = append(quads, q)
+		return nil
+	}); err != nil {
+		return err
+	}
+	tx, err := rgraph.ReplaceQuads(ctx, st, quads)
+	if err != nil {
+		return err
 	}
+	return st.ApplyTransaction(tx)
 }
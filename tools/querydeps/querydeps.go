@@ -0,0 +1,96 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Program querydeps evaluates a query against the quad store maintained by a
+// depserver started with -query-store.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/creachadair/repodeps/client"
+	"github.com/creachadair/repodeps/service"
+)
+
+var (
+	address  = flag.String("address", os.Getenv("DEPSERVER_ADDR"), "Service address")
+	language = flag.String("lang", service.QueryLanguages[0], "Query language ("+strings.Join(service.QueryLanguages, ", ")+")")
+	limit    = flag.Int("limit", 0, "Maximum number of results (0 for the server's limit)")
+	timeout  = flag.Duration("timeout", 0, "Maximum query time (0 for the server's limit)")
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %[1]s [options] <query>...
+
+Evaluate a query against the quad store mirroring the graph. The arguments are
+joined to form the text of the query; if there are none, the query is read
+from stdin. Each result is written to stdout as a JSON text.
+
+The store uses the vocabulary written by quaddeps -stable; for example, the
+packages that import github.com/pkg/errors are found by the Gizmo query:
+
+   g.V("<dep:pkg/github.com/pkg/errors>").In("<dep:imports>").All()
+
+Options:
+`, filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+	text := strings.Join(flag.Args(), " ")
+	if text == "" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatalf("Reading query: %v", err)
+		}
+		text = string(data)
+	}
+
+	ctx := context.Background()
+	c, err := client.Dial(ctx, *address)
+	if err != nil {
+		log.Fatalf("Dialing service: %v", err)
+	}
+	defer c.Close()
+
+	rsp, err := c.Query(ctx, &service.QueryReq{
+		Language: *language,
+		Query:    text,
+		Limit:    *limit,
+		Timeout:  *timeout,
+	})
+	if err != nil {
+		log.Fatalf("Query failed: %v", err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	for _, res := range rsp.Results {
+		if err := enc.Encode(res); err != nil {
+			log.Fatalf("Encoding result: %v", err)
+		}
+	}
+	if rsp.Truncated {
+		log.Printf("Results truncated after %d", len(rsp.Results))
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 
 	"github.com/creachadair/repodeps/client"
 	"github.com/creachadair/repodeps/graph"
@@ -37,8 +38,45 @@ var (
 	rowLimit     = flag.Int("limit", 0, "List at most this many matching rows (0 = no limit)")
 	matchPackage = flag.String("pkg", "", "Match this package or prefix with /...")
 	matchRepo    = flag.String("repo", "", "List only rows matching this repository")
+	matchQuery   = flag.String("q", "", "List only rows satisfying this query (see -help)")
+	storageTag   = flag.String("tag", "", "List rows stored under this tag")
+	minDeps      = flag.Int64("min-dependents", 0, "List only rows with at least this many transitive dependents")
+	minDepRepos  = flag.Int64("min-dependent-repos", 0, "List only rows with at least this many dependent repositories")
+	sortBy       = flag.String("sort", "", `Sort rows by "dependents", "dependentRepos", or "ranking" (descending)`)
 )
 
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %[1]s [options] [package]
+
+List the rows of the graph matching the specified package, or all rows if no
+package is given.
+
+A -q query is a sequence of terms separated by whitespace, all of which must
+match. A term prefixed by "-" is negated. The terms are:
+
+   path:p           -- the import path matches p (the field may be omitted)
+   name:p           -- the package name matches p
+   repo:p           -- the repository URL, with or without scheme, matches p
+   module:p         -- the module path matches p
+   imports:p        -- some direct dependency matches p
+   file:p           -- some source file matches p
+   type:t           -- the package type is unknown, stdlib, library, or program
+   rank>v           -- compare the ranking to v (also =, <, <=, >=)
+   dependents>v     -- compare the number of transitive dependents to v
+   dependentRepos>v -- compare the number of dependent repositories to v
+
+In a pattern, "*" matches any string, and a pattern ending in "/..." matches
+the path before it and any path beneath it. For example:
+
+   %[1]s -q 'type:program repo:github.com/foo/* -*/internal/*'
+
+Options:
+`, filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+}
+
 func main() {
 	flag.Parse()
 
@@ -55,11 +93,16 @@ func main() {
 
 	enc := json.NewEncoder(os.Stdout)
 	nr, err := c.Match(ctx, &service.MatchReq{
-		Package:      *matchPackage,
-		Repository:   *matchRepo,
-		CountOnly:    *doCountOnly,
-		IncludeFiles: *doFiles,
-		Limit:        *rowLimit,
+		Package:           *matchPackage,
+		Repository:        *matchRepo,
+		Query:             *matchQuery,
+		Tag:               *storageTag,
+		CountOnly:         *doCountOnly,
+		IncludeFiles:      *doFiles,
+		MinDependents:     *minDeps,
+		MinDependentRepos: *minDepRepos,
+		SortBy:            *sortBy,
+		Limit:             *rowLimit,
 	}, func(row *graph.Row) error {
 		if *doKeysOnly {
 			fmt.Println(row.ImportPath)
@@ -72,6 +115,8 @@ func main() {
 		log.Printf("Match failed: %v", err)
 	} else if *doCountOnly {
 		fmt.Println(nr)
+	} else if nr == 0 && *matchQuery != "" {
+		log.Printf("No packages matching %q", *matchQuery)
 	} else if nr == 0 {
 		log.Printf("No packages matching %q", *matchPackage)
 	}
@@ -34,9 +34,11 @@ var (
 	countOnly  = flag.Bool("count", false, "Count the number of matching dependencies")
 	filterSame = flag.Bool("filter-same-repo", false, "Exclude dependencies from the same repository")
 	filterDom  = flag.Bool("domain-only", false, "Exclude local and intrinsic imports")
+	filterVis  = flag.Bool("exclude-internal", false, "Exclude imports that violate internal visibility")
 	matchExpr  = flag.String("matching", "", "Select dependencies matching this regexp")
 	doComplete = flag.Bool("complete", false, "Report the full row for each importer")
 	limit      = flag.Int("limit", 0, "Return at most this many results")
+	storageTag = flag.String("tag", "", "Search the rows stored under this tag")
 )
 
 func init() {
@@ -73,12 +75,14 @@ func main() {
 
 	enc := json.NewEncoder(os.Stdout)
 	nr, err := c.Reverse(ctx, &service.ReverseReq{
-		Package:        flag.Args(),
-		CountOnly:      *countOnly,
-		FilterSameRepo: *filterSame,
-		Matching:       *matchExpr,
-		Complete:       *doComplete,
-		Limit:          *limit,
+		Package:         flag.Args(),
+		Tag:             *storageTag,
+		CountOnly:       *countOnly,
+		FilterSameRepo:  *filterSame,
+		ExcludeInternal: *filterVis,
+		Matching:        *matchExpr,
+		Complete:        *doComplete,
+		Limit:           *limit,
 	}, func(dep *service.ReverseDep) error {
 		if _, ok := deps.HasDomain(dep.Source); ok || !*filterDom {
 			enc.Encode(dep)
@@ -0,0 +1,101 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Program searchdeps finds packages by name or import path fragment, using
+// the search index of a depserver started with -search-index.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/creachadair/repodeps/client"
+	"github.com/creachadair/repodeps/service"
+)
+
+var (
+	address    = flag.String("address", os.Getenv("DEPSERVER_ADDR"), "Service address")
+	storageTag = flag.String("tag", "", "Search rows stored under this tag")
+	maxResults = flag.Int("limit", 0, "Report at most this many results (0 for the server's default)")
+	doKeysOnly = flag.Bool("keys", false, "Print only import paths, not full results")
+	doReindex  = flag.Bool("reindex", false, "Rebuild the search index before searching")
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %[1]s [options] <word>...
+
+Find packages whose names or import paths contain words beginning with each
+of the given words. The best matches are printed first, one JSON text each.
+With -reindex, the search index is rebuilt first; this is needed once for
+rows stored before the index was enabled, and requires a write token if the
+server is so configured.
+
+Options:
+`, filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 && !*doReindex {
+		log.Fatalf("Usage: %s [options] <word>...", filepath.Base(os.Args[0]))
+	}
+
+	ctx := context.Background()
+	c, err := client.Dial(ctx, *address)
+	if err != nil {
+		log.Fatalf("Dialing service: %v", err)
+	}
+	defer c.Close()
+
+	if *doReindex {
+		c.SetToken(os.Getenv("DEPSERVER_WRITE_TOKEN"))
+		rsp, err := c.Reindex(ctx, &service.ReindexReq{Tag: *storageTag})
+		if err != nil {
+			log.Fatalf("Reindex failed: %v", err)
+		}
+		log.Printf("Indexed %d rows in %v", rsp.NumRows, rsp.Elapsed)
+		if flag.NArg() == 0 {
+			return
+		}
+	}
+
+	rsp, err := c.Search(ctx, &service.SearchReq{
+		Query: strings.Join(flag.Args(), " "),
+		Tag:   *storageTag,
+		Limit: *maxResults,
+	})
+	if err != nil {
+		log.Fatalf("Search failed: %v", err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	for _, res := range rsp.Results {
+		if *doKeysOnly {
+			fmt.Println(res.ImportPath)
+		} else if err := enc.Encode(res); err != nil {
+			log.Fatalf("Encoding result: %v", err)
+		}
+	}
+	if len(rsp.Results) < rsp.NumMatches {
+		log.Printf("Showing %d of %d matches", len(rsp.Results), rsp.NumMatches)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Program sqldeps exports the dependency databases into a SQLite database for
+// ad-hoc SQL queries.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/creachadair/repodeps/export"
+	"github.com/creachadair/repodeps/poll"
+	"github.com/creachadair/repodeps/tools"
+
+	_ "modernc.org/sqlite" // register the "sqlite" driver
+)
+
+var (
+	graphDB    = flag.String("graph-db", os.Getenv("DEPSERVER_DB"), "Graph database path (required)")
+	repoDB     = flag.String("repo-db", os.Getenv("DEPSERVER_REPO_DB"), "Repository database path (optional)")
+	outputPath = flag.String("output", "", "Output SQLite database path (required)")
+	replace    = flag.Bool("replace", false, "Replace the output database if it exists")
+	storageTag = flag.String("tag", "", "Export the rows stored under this tag")
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %[1]s [options] -output <path>
+
+Export the rows of the graph, and the statuses and aliases of the repository
+database if -repo-db is set, into a new SQLite database. The tables are:
+
+   repositories   (id, url)
+   packages       (id, import_path, indexed, name, type, repository_id,
+                   repository_url, module, ranking, dependents,
+                   dependent_repos)
+   imports        (package_id, import_id)
+   files          (package_id, repo_path, digest)
+   statuses       (repository_id, tag, ref_name, digest, error_count,
+                   prefix, last_check)
+   status_updates (repository_id, tag, time, digest)
+   aliases        (alias, repository_id)
+
+Repositories are identified by their canonical URLs, so that packages can be
+joined with statuses and aliases; the repository_url of a package is the URL
+recorded in its row. Dependencies without a row of their own appear in
+packages with indexed = 0.
+
+For example, to list the most-imported packages:
+
+   SELECT p.import_path, count(*) AS n
+     FROM imports i JOIN packages p ON p.id = i.import_id
+    GROUP BY p.id ORDER BY n DESC LIMIT 10;
+
+Options:
+`, filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+	if *outputPath == "" {
+		log.Fatal("You must provide an -output path")
+	}
+	if _, err := os.Stat(*outputPath); err == nil {
+		if !*replace {
+			log.Fatalf("Output %q already exists (use -replace to replace it)", *outputPath)
+		} else if err := os.Remove(*outputPath); err != nil {
+			log.Fatalf("Removing old output: %v", err)
+		}
+	}
+
+	g, gc, err := tools.OpenGraph(*graphDB)
+	if err != nil {
+		log.Fatalf("Opening graph: %v", err)
+	}
+	defer gc.Close()
+	var repos *poll.DB
+	if *repoDB != "" {
+		db, rc, err := tools.OpenRepoDB(*repoDB)
+		if err != nil {
+			log.Fatalf("Opening repository database: %v", err)
+		}
+		defer rc.Close()
+		repos = db
+	}
+
+	db, err := sql.Open("sqlite", *outputPath)
+	if err != nil {
+		log.Fatalf("Opening output: %v", err)
+	}
+	ctx := context.Background()
+	if err := export.WriteSQLite(ctx, db, g.Tag(*storageTag), repos); err != nil {
+		db.Close()
+		log.Fatalf("Writing output: %v", err)
+	} else if err := db.Close(); err != nil {
+		log.Fatalf("Closing output: %v", err)
+	}
+}
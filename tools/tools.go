@@ -21,9 +21,11 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/creachadair/badgerstore"
 	"github.com/creachadair/repodeps/graph"
+	"github.com/creachadair/repodeps/poll"
 	"github.com/creachadair/repodeps/storage"
 )
 
@@ -34,7 +36,18 @@ func OpenGraph(path string) (*graph.Graph, io.Closer, error) {
 	if err != nil {
 		return nil, nil, fmt.Errorf("opening storage: %v", err)
 	}
-	return graph.New(storage.NewBlob(s)), s, nil
+	return graph.New(storage.NewBlob(s), nil), s, nil
+}
+
+// OpenRepoDB opens a read-only view of the repository database named by path.
+// The caller must ensure the closer is closed when the database is no longer
+// in use.
+func OpenRepoDB(path string) (*poll.DB, io.Closer, error) {
+	s, err := badgerstore.NewPathReadOnly(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening storage: %v", err)
+	}
+	return poll.NewDB(storage.NewBlob(s)), s, nil
 }
 
 // Inputs returns a channel that delivers the paths of inputs and is closed
@@ -59,3 +72,15 @@ func Inputs(readStdin bool) <-chan string {
 	}
 	return ch
 }
+
+// SplitList splits a comma-separated list of flag values, discarding empty
+// elements and surrounding whitespace.
+func SplitList(s string) []string {
+	var out []string
+	for _, elt := range strings.Split(s, ",") {
+		if t := strings.TrimSpace(elt); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -22,21 +22,43 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 
-	"bitbucket.org/creachadair/stringset"
 	"github.com/creachadair/repodeps/client"
-	"github.com/creachadair/repodeps/graph"
 	"github.com/creachadair/repodeps/service"
 )
 
 var (
 	address = flag.String("address", os.Getenv("DEPSERVER_ADDR"), "Service address")
 
-	noStdLib = flag.Bool("no-stdlib", false, "Filter out standard library packages")
+	noStdLib   = flag.Bool("no-stdlib", false, "Filter out standard library packages")
+	domainOnly = flag.Bool("domain-only", false, "Filter out packages without an import domain")
+	reverse    = flag.Bool("reverse", false, "List reverse (importing) rather than forward dependencies")
+	maxDepth   = flag.Int("depth", 0, "List only packages at most this many imports away (0 = no limit)")
+	showDepth  = flag.Bool("show-depth", false, "Print the depth of each package before its import path")
+	countOnly  = flag.Bool("count", false, "Count the number of packages in the closure")
+	storageTag = flag.String("tag", "", "Search the rows stored under this tag")
 )
 
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %[1]s [options] <package>...
+
+Print the transitive closure of the dependencies of the given packages,
+including the packages themselves, one per line in order by import path. If a
+package ends with "/...", every package with that prefix is included.
+
+Options:
+`, filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+}
+
 func main() {
 	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatal("You must provide at least one package")
+	}
 
 	ctx := context.Background()
 	c, err := client.Dial(ctx, *address)
@@ -45,33 +67,25 @@ func main() {
 	}
 	defer c.Close()
 
-	seen := stringset.New()
-	dead := stringset.New()
-	todo := stringset.New(flag.Args()...)
-	for len(todo) != 0 {
-		for pkg := range todo {
-			todo.Discard(pkg)
-			if seen.Contains(pkg) {
-				continue
-			}
-			c.Match(ctx, &service.MatchReq{Package: pkg}, func(row *graph.Row) error {
-				if seen.Contains(row.ImportPath) {
-					return nil
-				}
-				seen.Add(row.ImportPath)
-				if *noStdLib && row.Type == graph.Row_STDLIB {
-					dead.Add(row.ImportPath)
-				}
-				for _, dep := range row.Directs {
-					todo.Add(dep)
-				}
-				return nil
-			})
+	nr, err := c.Closure(ctx, &service.ClosureReq{
+		Package:       flag.Args(),
+		Reverse:       *reverse,
+		MaxDepth:      *maxDepth,
+		ExcludeStdlib: *noStdLib,
+		DomainOnly:    *domainOnly,
+		Tag:           *storageTag,
+		CountOnly:     *countOnly,
+	}, func(dep *service.ClosureDep) error {
+		if *showDepth {
+			fmt.Println(dep.Depth, dep.Package)
+		} else {
+			fmt.Println(dep.Package)
 		}
-		todo.Remove(seen)
-	}
-	seen.Remove(dead)
-	for _, pkg := range seen.Elements() {
-		fmt.Println(pkg)
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Closure failed: %v", err)
+	} else if *countOnly {
+		fmt.Println(nr)
 	}
 }
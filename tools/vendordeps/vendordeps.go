@@ -0,0 +1,83 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Program vendordeps lists the repositories that vendor a module.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/creachadair/repodeps/client"
+	"github.com/creachadair/repodeps/service"
+)
+
+var (
+	address   = flag.String("address", os.Getenv("DEPSERVER_ADDR"), "Service address")
+	countOnly = flag.Bool("count", false, "Count the matching (repository, module) pairs")
+	doPkgs    = flag.Bool("packages", false, "Include the vendored packages")
+	limit     = flag.Int("limit", 0, "Return at most this many results")
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %[1]s [options] <module>
+
+Print the repositories that vendor the named module, according to the
+vendor/modules.txt file of each repository. If the module ends with "/...", it
+matches any module with the given prefix. Each output is a JSON text:
+
+   {"repository": url, "module": module-path, "version": module-version}
+
+Options:
+`, filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 1 {
+		log.Fatal("You must provide exactly one module path to match")
+	}
+
+	ctx := context.Background()
+	c, err := client.Dial(ctx, *address)
+	if err != nil {
+		log.Fatalf("Dialing service: %v", err)
+	}
+	defer c.Close()
+
+	enc := json.NewEncoder(os.Stdout)
+	nr, err := c.Vendored(ctx, &service.VendoredReq{
+		Module:          flag.Arg(0),
+		CountOnly:       *countOnly,
+		IncludePackages: *doPkgs,
+		Limit:           *limit,
+	}, func(mod *service.VendoredModule) error {
+		return enc.Encode(mod)
+	})
+	if err != nil {
+		log.Printf("Vendored failed: %v", err)
+	} else if *countOnly {
+		fmt.Println(nr)
+	} else if nr == 0 {
+		log.Printf("No repositories vendoring %q", flag.Arg(0))
+	}
+}
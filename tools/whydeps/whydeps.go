@@ -0,0 +1,97 @@
+// Copyright 2019 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Program whydeps explains why one package depends on another, by printing
+// the shortest chains of imports between them.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/creachadair/repodeps/client"
+	"github.com/creachadair/repodeps/service"
+)
+
+var (
+	address    = flag.String("address", os.Getenv("DEPSERVER_ADDR"), "Service address")
+	fromRepo   = flag.String("repo", "", "Start from the packages of this repository")
+	numPaths   = flag.Int("n", 1, "Print up to this many chains, shortest first")
+	noStdLib   = flag.Bool("no-stdlib", false, "Do not follow chains through standard library packages")
+	noTests    = flag.Bool("no-tests", false, "Do not follow chains through test-support packages")
+	storageTag = flag.String("tag", "", "Search the rows stored under this tag")
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %[1]s [options] <source>... <target>
+       %[1]s [options] -repo <url> <target>
+
+Print the shortest chains of direct imports by which the source packages (or
+the packages of a repository) depend on the target package. If a source ends
+with "/...", any package with that prefix is a source. Each chain is printed
+on one line, from source to target.
+
+With -no-tests, chains through packages with an import path element of "test",
+"testing", "testutil", or "httptest", or ending in "_test", are skipped. The
+graph does not record which imports are used only by tests, so this is only a
+heuristic based on package names.
+
+Options:
+`, filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+	args := flag.Args()
+	if len(args) == 0 {
+		log.Fatal("You must provide a target package")
+	} else if len(args) == 1 && *fromRepo == "" {
+		log.Fatal("You must provide at least one source package or -repo")
+	}
+
+	ctx := context.Background()
+	c, err := client.Dial(ctx, *address)
+	if err != nil {
+		log.Fatalf("Dialing service: %v", err)
+	}
+	defer c.Close()
+
+	target := args[len(args)-1]
+	rsp, err := c.Path(ctx, &service.PathReq{
+		From:          args[:len(args)-1],
+		Repository:    *fromRepo,
+		To:            target,
+		Tag:           *storageTag,
+		ExcludeStdlib: *noStdLib,
+		ExcludeTests:  *noTests,
+		Limit:         *numPaths,
+	})
+	if err != nil {
+		log.Fatalf("Path failed: %v", err)
+	} else if rsp.NumPaths == 0 {
+		log.Printf("No dependency on %q found", target)
+		os.Exit(1)
+	}
+	for _, path := range rsp.Paths {
+		fmt.Println(strings.Join(path, " → "))
+	}
+}